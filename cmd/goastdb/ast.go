@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// astNode is one nodes row, with its children when it is part of the dumped
+// subtree.
+type astNode struct {
+	Ordinal       int        `json:"ordinal"`
+	ParentOrdinal *int       `json:"parent_ordinal"`
+	Kind          string     `json:"kind"`
+	Field         string     `json:"field,omitempty"`
+	FieldIndex    *int       `json:"field_index,omitempty"`
+	Text          string     `json:"node_text,omitempty"`
+	StartLine     int        `json:"start_line"`
+	StartCol      int        `json:"start_col"`
+	EndLine       int        `json:"end_line"`
+	EndCol        int        `json:"end_col"`
+	StartOffset   int        `json:"start_offset"`
+	EndOffset     int        `json:"end_offset"`
+	Children      []*astNode `json:"children,omitempty"`
+	// Truncated counts children left out by --depth.
+	Truncated int `json:"truncated_children,omitempty"`
+}
+
+// astDump is a node, the chain of nodes above it from the file root down, and
+// its subtree.
+type astDump struct {
+	FilePath  string     `json:"file_path"`
+	FileID    int64      `json:"file_id"`
+	Ancestors []*astNode `json:"ancestors"`
+	Node      *astNode   `json:"node"`
+}
+
+// astTarget selects the node to dump: the file root when Line is 0, the
+// outermost node starting on Line when Col is 0, and otherwise the innermost
+// node containing Line:Col.
+type astTarget struct {
+	FilePath string
+	FileID   int64
+	Ordinal  int // used with FileID
+	Line     int
+	Col      int
+}
+
+func runASTCommand(args []string) {
+	fs := flag.NewFlagSet("ast", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	depth := fs.Int("depth", 0, "levels of the subtree to print below the node (0 for all)")
+	fileID := fs.Int64("file-id", -1, "file_id of the node to dump, with --ordinal")
+	ordinal := fs.Int("ordinal", -1, "ordinal of the node to dump, with --file-id")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb ast [flags] <file>[:line[:col]]")
+		fmt.Fprintln(os.Stderr, "       goastdb ast [flags] --file-id <id> --ordinal <n>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Prints the indexed syntax tree of a file, of the outermost node starting on a")
+		fmt.Fprintln(os.Stderr, "line, or of the innermost node at a line and column, below its ancestors.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var target astTarget
+	switch {
+	case len(rest) == 1 && *fileID < 0 && *ordinal < 0:
+		target, err = parseASTTarget(*repo, rest[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		target.FileID = -1
+	case len(rest) == 0 && *fileID >= 0 && *ordinal >= 0:
+		target = astTarget{FileID: *fileID, Ordinal: *ordinal}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		log.Fatalf("open duckdb: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	dump, err := loadAST(context.Background(), db, target, *depth)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(dump); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	writeAST(os.Stdout, dump)
+}
+
+// parseASTTarget splits file[:line[:col]] and makes file relative to repo
+// when it names a file on disk; otherwise it is taken as repo-relative.
+func parseASTTarget(repo, spec string) (astTarget, error) {
+	file := spec
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndexByte(file, ':')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[i+1:])
+		if err != nil {
+			break
+		}
+		if n < 1 {
+			return astTarget{}, fmt.Errorf("invalid position %q: lines and columns start at 1", spec)
+		}
+		nums = append([]int{n}, nums...)
+		file = file[:i]
+	}
+	if file == "" {
+		return astTarget{}, fmt.Errorf("invalid position %q: missing file", spec)
+	}
+	t := astTarget{FilePath: filepath.ToSlash(filepath.Clean(file))}
+	if len(nums) > 0 {
+		t.Line = nums[0]
+	}
+	if len(nums) > 1 {
+		t.Col = nums[1]
+	}
+	if _, err := os.Stat(file); err == nil {
+		absFile, err1 := filepath.Abs(file)
+		absRepo, err2 := filepath.Abs(repo)
+		if err1 == nil && err2 == nil {
+			if rel, err := filepath.Rel(absRepo, absFile); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				t.FilePath = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return t, nil
+}
+
+// loadAST reads the target's file from nodes and assembles the dump. A
+// positive depth limits how many levels below the node are included.
+func loadAST(ctx context.Context, db *sql.DB, target astTarget, depth int) (astDump, error) {
+	dump := astDump{FilePath: target.FilePath, FileID: target.FileID, Ancestors: []*astNode{}}
+	var err error
+	if target.FileID >= 0 {
+		err = db.QueryRowContext(ctx, `SELECT path FROM files WHERE file_id = ?`, target.FileID).Scan(&dump.FilePath)
+	} else {
+		err = db.QueryRowContext(ctx, `SELECT file_id FROM files WHERE path = ?`, target.FilePath).Scan(&dump.FileID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if target.FileID >= 0 {
+			return dump, fmt.Errorf("no file with file_id %d", target.FileID)
+		}
+		return dump, fmt.Errorf("%s is not indexed", target.FilePath)
+	}
+	if err != nil {
+		return dump, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT ordinal, parent_ordinal, kind, coalesce(field, ''), field_index, coalesce(node_text, ''),
+  start_line, start_col, end_line, end_col, start_offset, end_offset
+FROM nodes WHERE file_id = ? ORDER BY ordinal`, dump.FileID)
+	if err != nil {
+		return dump, err
+	}
+	defer func() { _ = rows.Close() }()
+	var nodes []*astNode
+	byOrdinal := make(map[int]*astNode)
+	for rows.Next() {
+		var (
+			n             astNode
+			parent, index sql.NullInt64
+		)
+		if err := rows.Scan(&n.Ordinal, &parent, &n.Kind, &n.Field, &index, &n.Text,
+			&n.StartLine, &n.StartCol, &n.EndLine, &n.EndCol, &n.StartOffset, &n.EndOffset); err != nil {
+			return dump, err
+		}
+		if parent.Valid {
+			p := int(parent.Int64)
+			n.ParentOrdinal = &p
+		}
+		if index.Valid {
+			i := int(index.Int64)
+			n.FieldIndex = &i
+		}
+		nodes = append(nodes, &n)
+		byOrdinal[n.Ordinal] = &n
+	}
+	if err := rows.Err(); err != nil {
+		return dump, err
+	}
+	if len(nodes) == 0 {
+		return dump, fmt.Errorf("%s has no nodes (see files.parse_error)", dump.FilePath)
+	}
+
+	node := selectASTNode(nodes, byOrdinal, target)
+	if node == nil {
+		if target.FileID >= 0 {
+			return dump, fmt.Errorf("%s has no node with ordinal %d", dump.FilePath, target.Ordinal)
+		}
+		if target.Col == 0 {
+			return dump, fmt.Errorf("no node starts on %s:%d", dump.FilePath, target.Line)
+		}
+		return dump, fmt.Errorf("no node at %s:%d:%d", dump.FilePath, target.Line, target.Col)
+	}
+	for p := node.ParentOrdinal; p != nil; {
+		a := byOrdinal[*p]
+		if a == nil {
+			break
+		}
+		dump.Ancestors = append([]*astNode{a}, dump.Ancestors...)
+		p = a.ParentOrdinal
+	}
+
+	// Nodes are in pre-order, so each parent is placed before its children.
+	levels := map[int]int{node.Ordinal: 0}
+	for _, n := range nodes {
+		if n.Ordinal <= node.Ordinal || n.ParentOrdinal == nil {
+			continue
+		}
+		level, ok := levels[*n.ParentOrdinal]
+		if !ok {
+			continue
+		}
+		parent := byOrdinal[*n.ParentOrdinal]
+		if depth > 0 && level >= depth {
+			parent.Truncated++
+			continue
+		}
+		levels[n.Ordinal] = level + 1
+		parent.Children = append(parent.Children, n)
+	}
+	dump.Node = node
+	return dump, nil
+}
+
+func selectASTNode(nodes []*astNode, byOrdinal map[int]*astNode, target astTarget) *astNode {
+	switch {
+	case target.FileID >= 0:
+		return byOrdinal[target.Ordinal]
+	case target.Line == 0:
+		return nodes[0]
+	case target.Col == 0:
+		for _, n := range nodes {
+			if n.StartLine == target.Line {
+				return n
+			}
+		}
+		return nil
+	}
+	var found *astNode
+	for _, n := range nodes {
+		startsBefore := n.StartLine < target.Line || (n.StartLine == target.Line && n.StartCol <= target.Col)
+		endsAfter := n.EndLine > target.Line || (n.EndLine == target.Line && n.EndCol > target.Col)
+		if startsBefore && endsAfter {
+			found = n
+		}
+	}
+	return found
+}
+
+// writeAST prints the ancestors and the subtree one node per line, indented
+// by depth:
+//
+//	Args[0] *ast.Ident x #12 5:14-5:15
+func writeAST(w io.Writer, dump astDump) {
+	fmt.Fprintf(w, "%s (file_id %d)\n", dump.FilePath, dump.FileID)
+	for i, a := range dump.Ancestors {
+		writeASTLine(w, i, a)
+	}
+	var walk func(n *astNode, level int)
+	walk = func(n *astNode, level int) {
+		writeASTLine(w, level, n)
+		for _, c := range n.Children {
+			walk(c, level+1)
+		}
+		if n.Truncated > 0 {
+			fmt.Fprintf(w, "%s... %d more\n", strings.Repeat("  ", level+1), n.Truncated)
+		}
+	}
+	walk(dump.Node, len(dump.Ancestors))
+}
+
+func writeASTLine(w io.Writer, level int, n *astNode) {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("  ", level))
+	if n.Field != "" {
+		sb.WriteString(n.Field)
+		if n.FieldIndex != nil {
+			fmt.Fprintf(&sb, "[%d]", *n.FieldIndex)
+		}
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(n.Kind)
+	if n.Text != "" {
+		sb.WriteString(" " + strings.ReplaceAll(n.Text, "\n", `\n`))
+	}
+	fmt.Fprintf(&sb, " #%d %d:%d-%d:%d", n.Ordinal, n.StartLine, n.StartCol, n.EndLine, n.EndCol)
+	fmt.Fprintln(w, sb.String())
+}
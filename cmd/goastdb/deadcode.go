@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+func runDeadcodeCommand(args []string) {
+	fs := flag.NewFlagSet("deadcode", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "text", formatFlagUsage)
+	reachableFrom := fs.String("reachable-from", "", "report symbols unreachable from main, tests or all instead of unreferenced ones")
+	exported := fs.Bool("exported", true, "also report exported symbols no indexed package uses")
+	pathPrefix := fs.String("path-prefix", "", "only include files whose path starts with this prefix")
+	limit := fs.Int("limit", 500, "maximum symbols shown")
+	var allow stringList
+	fs.Var(&allow, "allow", "path prefix whose symbols are always kept, e.g. a reflection-heavy package (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb deadcode [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Lists package-level functions, methods, types, constants and variables that")
+		fmt.Fprintln(os.Stderr, "nothing references (the DEAD_CODE helper). References are matched by name, so")
+		fmt.Fprintln(os.Stderr, "the report errs toward keeping code.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	switch *reachableFrom {
+	case "", "main", "tests", "all":
+	default:
+		log.Fatalf("--reachable-from must be main, tests or all, got %q", *reachableFrom)
+	}
+
+	helpers, err := explore.SelectQueries([]string{"DEAD_CODE"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	helperArgs, err := helpers[0].Bind(map[string]string{
+		"reachable_from": *reachableFrom,
+		"allow":          strings.Join(allow, ","),
+		"exported":       strconv.FormatBool(*exported),
+		"path_prefix":    *pathPrefix,
+		"limit":          strconv.Itoa(*limit),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), helpers[0].SQL, helperArgs...)
+	if *format != "text" {
+		if err := writeTable(os.Stdout, *format, table); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	writeDeadSymbols(os.Stdout, table)
+}
+
+// writeDeadSymbols prints one file:line entry per symbol of a DEAD_CODE result.
+func writeDeadSymbols(w io.Writer, t governance.Table) {
+	col := make(map[string]int, len(t.Columns))
+	for i, c := range t.Columns {
+		col[c] = i
+	}
+	for _, row := range t.Rows {
+		cell := func(name string) string { return formatCell(row[col[name]]) }
+		fmt.Fprintf(w, "%s:%s-%s  %s  %s\n", cell("path"), cell("start_line"), cell("end_line"), cell("symbol_kind"), cell("symbol"))
+	}
+	if len(t.Rows) == 0 {
+		fmt.Fprintln(w, "no dead code found")
+		return
+	}
+	fmt.Fprintf(w, "%d unused symbols\n", len(t.Rows))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+func runDupesCommand(args []string) {
+	fs := flag.NewFlagSet("dupes", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "text", formatFlagUsage)
+	minLines := fs.Int("min-lines", 5, "minimum lines per copy")
+	minNodes := fs.Int("min-nodes", 30, "minimum AST nodes per copy")
+	pathPrefix := fs.String("path-prefix", "", "only include files whose path starts with this prefix")
+	limit := fs.Int("limit", 20, "maximum clone groups shown")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb dupes [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Lists groups of statements and functions with the same structure, ignoring")
+		fmt.Fprintln(os.Stderr, "identifier names and literal values, largest first (the DUPLICATE_CODE helper).")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+
+	helpers, err := explore.SelectQueries([]string{"DUPLICATE_CODE"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	helperArgs, err := helpers[0].Bind(map[string]string{
+		"min_lines":   strconv.Itoa(*minLines),
+		"min_nodes":   strconv.Itoa(*minNodes),
+		"path_prefix": *pathPrefix,
+		"limit":       strconv.Itoa(*limit),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), helpers[0].SQL, helperArgs...)
+	if *format != "text" {
+		if err := writeTable(os.Stdout, *format, table); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	writeCloneGroups(os.Stdout, table)
+}
+
+// writeCloneGroups prints each clone group of a DUPLICATE_CODE result with
+// the location of every copy.
+func writeCloneGroups(w io.Writer, t governance.Table) {
+	col := make(map[string]int, len(t.Columns))
+	for i, c := range t.Columns {
+		col[c] = i
+	}
+	groups := 0
+	prev := ""
+	for _, row := range t.Rows {
+		cell := func(name string) string { return formatCell(row[col[name]]) }
+		if g := cell("clone_group"); g != prev {
+			if prev != "" {
+				fmt.Fprintln(w)
+			}
+			prev = g
+			groups++
+			fmt.Fprintf(w, "clone group %s: %s copies of %s lines, %s nodes\n", g, cell("copies"), cell("lines"), cell("nodes"))
+		}
+		fmt.Fprintf(w, "  %s:%s-%s  %s  %s\n", cell("path"), cell("start_line"), cell("end_line"), cell("kind"), cell("function_name"))
+	}
+	if groups == 0 {
+		fmt.Fprintln(w, "no duplicate code found")
+	}
+}
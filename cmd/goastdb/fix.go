@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/edit"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+// fixOutcome summarizes applyFixes. Remaining are the violations left
+// unfixed and Fixed the ones whose fix was written; Failed is set when some
+// file could not be fixed.
+type fixOutcome struct {
+	Remaining []governance.Violation
+	Fixed     []governance.Violation
+	Fixes     int
+	Files     int
+	Failed    bool
+}
+
+// applyFixes prints a diff of each planned file when diff is set and writes
+// the fixed files when write is set.
+func applyFixes(stdout, stderr io.Writer, repoRoot string, plans []governance.FileFix, violations []governance.Violation, diff, write bool) fixOutcome {
+	// Plans share each violation's Fix pointer, which identifies it here.
+	applied := make(map[*governance.Fix]struct{})
+	var out fixOutcome
+	for _, p := range plans {
+		if p.Err != nil {
+			fmt.Fprintf(stderr, "fix %s: %v\n", p.FilePath, p.Err)
+			out.Failed = true
+			continue
+		}
+		for _, s := range p.Skipped {
+			fmt.Fprintf(stderr, "fix %s:%d: skipped %s fix: %s\n", p.FilePath, s.Violation.Line, s.Violation.RuleID, s.Reason)
+		}
+		if diff {
+			fmt.Fprint(stdout, edit.Unified(p.FilePath, p.Before, p.After))
+		}
+		if write {
+			abs := filepath.Join(repoRoot, filepath.FromSlash(p.FilePath))
+			info, err := os.Stat(abs)
+			if err == nil {
+				err = os.WriteFile(abs, p.After, info.Mode().Perm())
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "fix %s: %v\n", p.FilePath, err)
+				out.Failed = true
+				continue
+			}
+		}
+		for _, v := range p.Applied {
+			applied[v.Fix] = struct{}{}
+		}
+		out.Files++
+		out.Fixes += len(p.Applied)
+	}
+
+	out.Remaining = make([]governance.Violation, 0, len(violations))
+	for _, v := range violations {
+		if _, ok := applied[v.Fix]; ok && write {
+			out.Fixed = append(out.Fixed, v)
+			continue
+		}
+		out.Remaining = append(out.Remaining, v)
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+	"github.com/Yacobolo/goastdb/pkg/astdb/pattern"
+)
+
+func runGrepCommand(args []string) {
+	fs := flag.NewFlagSet("grep", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "text", formatFlagUsage)
+	showSQL := fs.Bool("sql", false, "print the SQL the pattern compiles to instead of running it")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb grep [flags] <pattern>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Finds code matching a Go pattern. $x matches any node, $*x any number of list")
+		fmt.Fprintln(os.Stderr, "elements; a name used twice must match the same code. Example:")
+		fmt.Fprintln(os.Stderr, "  goastdb grep 'if $err != nil { return $*_ }'")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	p, err := pattern.Parse(rest[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *showSQL {
+		fmt.Println(p.SQL())
+		return
+	}
+
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		log.Fatalf("open duckdb: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	matches, err := p.Find(context.Background(), db, *repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *format {
+	case "text":
+		writeMatches(os.Stdout, p, matches)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Pattern string          `json:"pattern"`
+			Matches []pattern.Match `json:"matches"`
+		}{Pattern: p.Source, Matches: matches}); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		if err := writeTable(os.Stdout, *format, matchTable(p, matches)); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// writeMatches prints one grep-style line per match, with its first source
+// line, followed by the wildcard bindings.
+func writeMatches(w io.Writer, p *pattern.Pattern, matches []pattern.Match) {
+	for _, m := range matches {
+		text, _, more := strings.Cut(m.Text, "\n")
+		if more {
+			text += " ..."
+		}
+		fmt.Fprintf(w, "%s:%d:%d: %s\n", m.FilePath, m.Line, m.Column, text)
+		for _, name := range p.Vars {
+			fmt.Fprintf(w, "    $%s = %s\n", name, oneLine(m.Bindings[name]))
+		}
+	}
+}
+
+func matchTable(p *pattern.Pattern, matches []pattern.Match) governance.Table {
+	t := governance.Table{Columns: []string{"file_path", "line", "column", "end_line", "end_column", "match"}}
+	for _, name := range p.Vars {
+		t.Columns = append(t.Columns, "$"+name)
+	}
+	for _, m := range matches {
+		row := []any{m.FilePath, m.Line, m.Column, m.EndLine, m.EndColumn, m.Text}
+		for _, name := range p.Vars {
+			row = append(row, m.Bindings[name])
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// oneLine collapses a multi-line binding so each binding stays on one line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/Yacobolo/goastdb/pkg/astdb"
 	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
 	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+	"github.com/Yacobolo/goastdb/pkg/astdb/mcp"
 )
 
 type outputEnvelope struct {
@@ -34,6 +35,30 @@ func main() {
 		runQueryCommand(os.Args[2:])
 	case "helper":
 		runHelperCommand(os.Args[2:])
+	case "mcp":
+		runMCPCommand(os.Args[2:])
+	case "ast":
+		runASTCommand(os.Args[2:])
+	case "dupes":
+		runDupesCommand(os.Args[2:])
+	case "deadcode":
+		runDeadcodeCommand(os.Args[2:])
+	case "grep":
+		runGrepCommand(os.Args[2:])
+	case "rewrite":
+		runRewriteCommand(os.Args[2:])
+	case "shell":
+		runShellCommand(os.Args[2:])
+	case "rules":
+		runRulesCommand(os.Args[2:])
+	case "check":
+		runCheckCommand(os.Args[2:])
+	case "trend":
+		runTrendCommand(os.Args[2:])
+	case "export":
+		runExportCommand(os.Args[2:])
+	case "import":
+		runImportCommand(os.Args[2:])
 	case "-h", "--help", "help":
 		printRootUsage()
 	default:
@@ -45,7 +70,11 @@ func runQueryCommand(args []string) {
 	fs := flag.NewFlagSet("query", flag.ExitOnError)
 	repo := fs.String("repo", ".", "repository root to scan")
 	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
-	format := fs.String("format", "text", "output format: text|json")
+	format := fs.String("format", "text", formatFlagUsage)
+	snapshot := fs.String("snapshot", "", "query a Parquet snapshot directory read-only instead of the DB")
+	var params, positional stringList
+	fs.Var(&params, "param", "bind $name placeholder as name=value or name:type=value (repeatable)")
+	fs.Var(&positional, "arg", "bind the next positional ?/$N placeholder (repeatable)")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, "Usage: goastdb query [flags] <sql>")
 		fmt.Fprintln(os.Stderr)
@@ -53,16 +82,26 @@ func runQueryCommand(args []string) {
 		fmt.Fprintln(os.Stderr)
 		fs.PrintDefaults()
 	}
-	if err := fs.Parse(args); err != nil {
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
 		log.Fatal(err)
 	}
-	if len(fs.Args()) != 1 {
+	if len(rest) != 1 {
 		fs.Usage()
 		os.Exit(2)
 	}
+	bindArgs, err := queryArgs(params, positional)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	sqlQuery := fs.Args()[0]
-	result, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), sqlQuery)
+	sqlQuery := rest[0]
+	if *snapshot != "" {
+		result, table := executeSnapshotQuery(*snapshot, sqlQuery, bindArgs...)
+		printQueryOutput(*format, outputEnvelope{Mode: "query", Result: result, Table: table})
+		return
+	}
+	result, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), sqlQuery, bindArgs...)
 	printQueryOutput(*format, outputEnvelope{Mode: "query", Result: result, Table: table})
 }
 
@@ -70,54 +109,199 @@ func runHelperCommand(args []string) {
 	fs := flag.NewFlagSet("helper", flag.ExitOnError)
 	repo := fs.String("repo", ".", "repository root to scan")
 	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
-	format := fs.String("format", "text", "output format: text|json")
+	format := fs.String("format", "text", formatFlagUsage)
+	var params, helperDirs stringList
+	fs.Var(&params, "param", "set a helper parameter as name=value (repeatable)")
+	fs.Var(&helperDirs, "helpers-dir", "extra directory of *.sql helpers, besides <repo>/.goast/helpers (repeatable)")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, "Usage: goastdb helper [flags] list")
-		fmt.Fprintln(os.Stderr, "       goastdb helper [flags] <id>")
+		fmt.Fprintln(os.Stderr, "       goastdb helper [flags] <id> [--param name=value ...]")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Lists helper queries or executes one helper query by ID.")
 		fmt.Fprintln(os.Stderr)
 		fs.PrintDefaults()
 	}
-	if err := fs.Parse(args); err != nil {
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	if len(fs.Args()) == 0 || fs.Args()[0] == "list" {
-		printHelperList(*format, explore.DefaultQueries())
+	if len(rest) == 0 || rest[0] == "list" {
+		helpers, err := selectHelpers(*repo, helperDirs, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printHelperList(*format, helpers)
 		return
 	}
-	if len(fs.Args()) != 1 {
+	if len(rest) != 1 {
 		fs.Usage()
 		os.Exit(2)
 	}
 
-	helperID := strings.TrimSpace(fs.Args()[0])
-	helpers, err := explore.SelectQueries([]string{helperID})
+	helperID := strings.TrimSpace(rest[0])
+	helpers, err := selectHelpers(*repo, helperDirs, []string{helperID})
 	if err != nil {
 		log.Fatal(err)
 	}
 	helper := helpers[0]
+	values, err := parseParamValues(params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	helperArgs, err := helper.Bind(values)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	result, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), helper.SQL)
+	result, table := executeQuery(*repo, resolveDuckDBPath(*repo, *duckdbPath), helper.SQL, helperArgs...)
 	printQueryOutput(*format, outputEnvelope{Mode: "helper", Result: result, Table: table, Helper: &helper})
 }
 
-func executeQuery(repo, duckdbPath, sqlQuery string) (astdb.Result, governance.Table) {
-	ctx := context.Background()
+func runMCPCommand(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	maxRows := fs.Int("max-rows", 0, "maximum rows per tool result (default 200)")
+	var helperDirs stringList
+	fs.Var(&helperDirs, "helpers-dir", "extra directory of *.sql helpers, besides <repo>/.goast/helpers (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb mcp [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Serves the AST database to coding agents over the Model Context Protocol (stdio).")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if len(fs.Args()) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	srv := mcp.NewServer(mcp.Config{
+		RepoRoot:   *repo,
+		DuckDBPath: resolveDuckDBPath(*repo, *duckdbPath),
+		MaxRows:    *maxRows,
+		HelperDirs: helperDirs,
+	})
+	if err := srv.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runExportCommand(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "parquet", "export format: parquet")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb export [flags] <dir>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Writes every table of the (freshly synced) AST database to <dir>, one file per table.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if len(fs.Args()) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "parquet" {
+		log.Fatalf("invalid -format %q (expected parquet)", *format)
+	}
+
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+	tables, err := astdb.Export(context.Background(), dbPath, fs.Args()[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("exported %d tables to %s: %s\n", len(tables), fs.Args()[0], strings.Join(tables, ", "))
+}
+
+func runImportCommand(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	force := fs.Bool("force", false, "overwrite an existing database")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb import [flags] <dir>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Recreates the AST database from a directory written by goastdb export.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if len(fs.Args()) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	if _, err := os.Stat(dbPath); err == nil && !*force {
+		log.Fatalf("database %s already exists (use --force to overwrite)", dbPath)
+	}
+	if err := astdb.Import(context.Background(), fs.Args()[0], dbPath); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("imported %s into %s\n", fs.Args()[0], dbPath)
+}
+
+func syncDatabase(repo, duckdbPath string) astdb.Result {
+	result := indexDatabase(repo, duckdbPath)
+	if err := governance.NewRunner(duckdbPath).SyncRuleFiles(context.Background(), repo); err != nil {
+		log.Fatal(err)
+	}
+	return result
+}
+
+// indexDatabase brings the AST index up to date without syncing rule files.
+// The index has no per-file update, so any source change rebuilds it from
+// the whole repository.
+func indexDatabase(repo, duckdbPath string) astdb.Result {
 	opts := astdb.DefaultOptions()
 	opts.RepoRoot = repo
 	opts.DuckDBPath = duckdbPath
 	opts.Mode = "query"
 	opts.QueryBench = false
 
-	result, err := astdb.Run(ctx, opts)
+	result, err := astdb.Run(context.Background(), opts)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if result.Sync.RolledBack {
+		fmt.Fprintf(os.Stderr, "warning: rebuild failed, using the previous index: %s\n", result.Sync.RollbackError)
+	}
+	return result
+}
 
-	runner := governance.NewRunner(opts.DuckDBPath)
-	table, err := runner.QueryTable(ctx, sqlQuery)
+func executeSnapshotQuery(dir, sqlQuery string, args ...any) (astdb.Result, governance.Table) {
+	ctx := context.Background()
+	db, err := astdb.OpenSnapshot(ctx, dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	table, err := governance.NewRunnerFromDB(db).QueryTable(ctx, sqlQuery, args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return astdb.Result{Sync: astdb.SyncStats{Action: "snapshot", Reason: dir}}, table
+}
+
+func executeQuery(repo, duckdbPath, sqlQuery string, args ...any) (astdb.Result, governance.Table) {
+	ctx := context.Background()
+	result := syncDatabase(repo, duckdbPath)
+
+	runner := governance.NewRunner(duckdbPath)
+	table, err := runner.QueryTable(ctx, sqlQuery, args...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -125,6 +309,9 @@ func executeQuery(repo, duckdbPath, sqlQuery string) (astdb.Result, governance.T
 }
 
 func printHelperList(format string, queries []explore.Query) {
+	if err := validateFormat(format); err != nil {
+		log.Fatal(err)
+	}
 	if format == "json" {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -138,16 +325,17 @@ func printHelperList(format string, queries []explore.Query) {
 
 	rows := make([][]any, 0, len(queries))
 	for _, q := range queries {
-		rows = append(rows, []any{q.ID, q.Description})
+		rows = append(rows, []any{q.ID, q.Description, describeParams(q.Params), q.Source})
+	}
+	t := governance.Table{Columns: []string{"id", "description", "params", "source"}, Rows: rows}
+	if err := writeTable(os.Stdout, format, t); err != nil {
+		log.Fatal(err)
 	}
-	t := governance.Table{Columns: []string{"id", "description"}, Rows: rows}
-	fmt.Println(formatTable(t))
-	fmt.Printf("(%d rows)\n", len(rows))
 }
 
 func printQueryOutput(format string, out outputEnvelope) {
-	if format != "text" && format != "json" {
-		log.Fatalf("invalid -format %q (expected text or json)", format)
+	if err := validateFormat(format); err != nil {
+		log.Fatal(err)
 	}
 	if format == "json" {
 		enc := json.NewEncoder(os.Stdout)
@@ -158,8 +346,9 @@ func printQueryOutput(format string, out outputEnvelope) {
 		return
 	}
 
-	fmt.Println(formatTable(out.Table))
-	fmt.Printf("(%d rows)\n", len(out.Table.Rows))
+	if err := writeTable(os.Stdout, format, out.Table); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func formatTable(t governance.Table) string {
@@ -268,11 +457,33 @@ Usage:
   goastdb query [flags] <sql>
   goastdb helper [flags] list
   goastdb helper [flags] <id>
+  goastdb ast [flags] <file>[:line[:col]]
+  goastdb grep [flags] <pattern>
+  goastdb rewrite [flags] <pattern> '->' <replacement>
+  goastdb dupes [flags]
+  goastdb deadcode [flags]
+  goastdb shell [flags]
+  goastdb rules [flags] list|add|enable|disable|remove|test
+  goastdb check [flags]
+  goastdb trend [flags]
+  goastdb mcp [flags]
+  goastdb export [flags] <dir>
+  goastdb import [flags] <dir>
 
 Examples:
   goastdb query "SELECT COUNT(*) AS files FROM files"
   goastdb helper list
-  goastdb helper LARGE_FUNCTIONS_BY_LINES
+  goastdb helper LARGE_FUNCTIONS_BY_LINES --param min_lines=80 --param path_prefix=internal/
+  goastdb query --param kind='*ast.GoStmt' 'SELECT COUNT(*) FROM nodes WHERE kind = $kind'
+  goastdb ast main.go:12:5
+  goastdb grep 'fmt.Errorf($msg, $*args)'
+  goastdb rewrite --diff 'errors.New(fmt.Sprintf($*args))' '->' 'fmt.Errorf($*args)'
+  goastdb dupes --min-lines 10
+  goastdb deadcode --reachable-from main --allow internal/plugins/
+  goastdb check --fail-on warning
+  goastdb trend --by category
+  goastdb export --format parquet ./snapshot
+  goastdb query --snapshot ./snapshot "SELECT COUNT(*) FROM nodes"
 
 Defaults:
   --repo defaults to current directory
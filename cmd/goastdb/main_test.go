@@ -1,10 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
 	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+	"github.com/Yacobolo/goastdb/pkg/astdb/pattern"
 )
 
 func TestResolveDuckDBPath_Default(t *testing.T) {
@@ -45,3 +58,482 @@ func TestFormatTable(t *testing.T) {
 		t.Fatalf("table missing row values: %s", s)
 	}
 }
+
+func TestWriteTable_Formats(t *testing.T) {
+	t.Parallel()
+
+	table := governance.Table{
+		Columns: []string{"path", "n"},
+		Types:   []string{"VARCHAR", "BIGINT"},
+		Rows: [][]any{
+			{"a|b.go", int64(3)},
+			{"c,d.go", nil},
+		},
+	}
+	cases := map[string]string{
+		"csv":      "path,n\na|b.go,3\n\"c,d.go\",\n",
+		"tsv":      "path\tn\na|b.go\t3\nc,d.go\t\n",
+		"ndjson":   "{\"path\":\"a|b.go\",\"n\":3}\n{\"path\":\"c,d.go\",\"n\":null}\n",
+		"markdown": "| path | n |\n| --- | --- |\n| a\\|b.go | 3 |\n| c,d.go | NULL |\n",
+	}
+	for format, want := range cases {
+		var b strings.Builder
+		if err := writeTable(&b, format, table); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if b.String() != want {
+			t.Fatalf("%s output mismatch:\ngot:\n%q\nwant:\n%q", format, b.String(), want)
+		}
+	}
+}
+
+func TestWriteTable_ArrowPreservesTypes(t *testing.T) {
+	t.Parallel()
+
+	table := governance.Table{
+		Columns: []string{"kind", "n", "line"},
+		Types:   []string{"VARCHAR", "BIGINT", "INTEGER"},
+		Rows:    [][]any{{"*ast.Ident", int64(10), int32(4)}},
+	}
+	var buf bytes.Buffer
+	if err := writeTable(&buf, "arrow", table); err != nil {
+		t.Fatalf("write arrow: %v", err)
+	}
+	rdr, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("read arrow: %v", err)
+	}
+	defer rdr.Release()
+	schema := rdr.Schema()
+	if got := schema.Field(1).Type.ID(); got != arrow.INT64 {
+		t.Fatalf("expected int64 column, got %s", got)
+	}
+	if got := schema.Field(2).Type.ID(); got != arrow.INT32 {
+		t.Fatalf("expected int32 column, got %s", got)
+	}
+	if !rdr.Next() || rdr.RecordBatch().NumRows() != 1 {
+		t.Fatal("expected one arrow row")
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	t.Parallel()
+
+	if err := validateFormat("yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if err := validateFormat("ndjson"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestShell_RunScript(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	syncDatabase(root, dbPath)
+
+	script := strings.Join([]string{
+		".tables",
+		"SELECT COUNT(*) AS n",
+		"FROM files;",
+		".format csv",
+		"SELECT kind FROM nodes WHERE kind = '*ast.FuncDecl';",
+		"SELECT 'a;",
+		"b' AS s -- trailing;",
+		";",
+		".nope",
+		"!1",
+	}, "\n")
+	var out bytes.Buffer
+	sh := &shell{
+		ctx:         context.Background(),
+		repo:        root,
+		duckdbPath:  dbPath,
+		historyPath: filepath.Join(root, ".goast", "shell_history"),
+		in:          bufio.NewScanner(strings.NewReader(script)),
+		out:         &out,
+		format:      "text",
+	}
+	if err := sh.open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer sh.close()
+	if err := sh.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"| nodes ", "| 1 |", "kind\n*ast.FuncDecl\n", "Error: unknown command .nope", "n\n1\n", "s\n\"a;\nb\"\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("shell output missing %q:\n%s", want, got)
+		}
+	}
+	if len(sh.history) != 4 {
+		t.Fatalf("expected 4 history entries, got %d: %q", len(sh.history), sh.history)
+	}
+}
+
+func TestShell_HelpersDir(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	helperDir := t.TempDir()
+	helper := "-- id: EXTRA_COUNT\n-- description: Extra helper\nSELECT COUNT(*) AS extra FROM files\n"
+	if err := os.WriteFile(filepath.Join(helperDir, "extra_count.sql"), []byte(helper), 0o644); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	syncDatabase(root, dbPath)
+
+	var out bytes.Buffer
+	sh := &shell{
+		ctx:        context.Background(),
+		repo:       root,
+		duckdbPath: dbPath,
+		helperDirs: []string{helperDir},
+		in:         bufio.NewScanner(strings.NewReader(".format csv\n.helpers\n.run EXTRA_COUNT\n")),
+		out:        &out,
+		format:     "text",
+	}
+	if err := sh.open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer sh.close()
+	if err := sh.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"EXTRA_COUNT,Extra helper,", "extra\n1\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("shell output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestStatementComplete(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT 1;\n", true},
+		{"SELECT 1\n", false},
+		{"SELECT 1; -- done\n", true},
+		{"SELECT 1 -- not yet;\n", false},
+		{"SELECT 'a;\n", false},
+		{"SELECT 'a;\nb';\n", true},
+		{"SELECT 'it''s;\n", false},
+		{"SELECT 'it''s';\n", true},
+		{"SELECT \"x;\n", false},
+		{"SELECT 1 /* ;\n", false},
+		{"SELECT 1 /* ; */;\n", true},
+		{"SELECT 1; SELECT 2\n", false},
+	} {
+		if got := statementComplete(tc.sql); got != tc.want {
+			t.Errorf("statementComplete(%q) = %v, want %v", tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestShell_HistoryIsTrimmed(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "shell_history")
+	var b strings.Builder
+	for i := 0; i < shellHistoryLimit+5; i++ {
+		fmt.Fprintf(&b, "SELECT %d;\x00\n", i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatalf("write history: %v", err)
+	}
+	entries := func() []string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read history: %v", err)
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\x00\n"), "\x00\n")
+	}
+
+	sh := &shell{historyPath: path}
+	sh.loadHistory()
+	if got := entries(); len(got) != shellHistoryLimit || got[0] != "SELECT 5;" {
+		t.Fatalf("expected the file trimmed to the newest %d entries, got %d starting %q", shellHistoryLimit, len(got), got[0])
+	}
+	sh.remember("SELECT 'new';")
+	if got := entries(); len(got) != shellHistoryLimit || got[0] != "SELECT 6;" || got[len(got)-1] != "SELECT 'new';" {
+		t.Fatalf("expected remember to keep the file at the limit, got %d entries", len(got))
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("helper", flag.ContinueOnError)
+	format := fs.String("format", "text", "")
+	var params stringList
+	fs.Var(&params, "param", "")
+	rest, err := parseInterspersed(fs, []string{"--format", "csv", "LARGE_FUNCTIONS_BY_LINES", "--param", "min_lines=80", "--param", "path_prefix=internal/"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(rest) != 1 || rest[0] != "LARGE_FUNCTIONS_BY_LINES" {
+		t.Fatalf("unexpected positionals: %q", rest)
+	}
+	if *format != "csv" || len(params) != 2 || params[1] != "path_prefix=internal/" {
+		t.Fatalf("unexpected flags: format=%q params=%q", *format, params)
+	}
+}
+
+func TestQueryArgs(t *testing.T) {
+	t.Parallel()
+
+	args, err := queryArgs([]string{"min:int=80", "prefix=cmd/"}, nil)
+	if err != nil {
+		t.Fatalf("query args: %v", err)
+	}
+	if args[0] != sql.Named("min", int64(80)) || args[1] != sql.Named("prefix", "cmd/") {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+	if _, err := queryArgs([]string{"a=1"}, []string{"2"}); err == nil {
+		t.Fatal("expected error when mixing --param and --arg")
+	}
+	if _, err := queryArgs([]string{"novalue"}, nil); err == nil {
+		t.Fatal("expected error for malformed --param")
+	}
+}
+
+func TestWriteViolations_GroupsBySeverity(t *testing.T) {
+	t.Parallel()
+
+	violations := []governance.Violation{
+		{RuleID: "W1", Severity: "warning", FilePath: "b.go", Line: 3, Detail: "meh"},
+		{RuleID: "C1", Severity: "critical", FilePath: "a.go", Line: 9, Symbol: "main", Detail: "bad"},
+		{RuleID: "W2", Severity: "warning", FilePath: "a.go", Line: 1, Detail: "meh"},
+	}
+	sortViolations(violations)
+	var buf bytes.Buffer
+	writeViolations(&buf, violations)
+	want := "CRITICAL (1)\n  a.go:9  C1  main: bad\n\nWARNING (2)\n  a.go:1  W2  meh\n  b.go:3  W1  meh\n\n3 violations (1 critical, 2 warning)\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	warnings := violations[1:]
+	for _, tc := range []struct {
+		failOn       string
+		all, warning bool
+	}{
+		{"critical", true, false},
+		{"error", true, false},
+		{"warning", true, true},
+		{"none", false, false},
+	} {
+		threshold, err := failThreshold(tc.failOn)
+		if err != nil {
+			t.Fatalf("fail-on %s: %v", tc.failOn, err)
+		}
+		if got := failing(violations, threshold); got != tc.all {
+			t.Fatalf("fail-on %s: got %v", tc.failOn, got)
+		}
+		if got := failing(warnings, threshold); got != tc.warning {
+			t.Fatalf("fail-on %s on warnings: got %v", tc.failOn, got)
+		}
+	}
+	if _, err := failThreshold("fatal"); err == nil {
+		t.Fatal("expected invalid fail-on error")
+	}
+
+	buf.Reset()
+	if err := writeTable(&buf, "csv", violationTable(violations[:1])); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if want := "rule_id,category,severity,file_path,line,column,symbol,detail\nC1,,critical,a.go,9,0,main,bad\n"; buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := writeTable(&buf, "arrow", violationTable(violations)); err != nil || buf.Len() == 0 {
+		t.Fatalf("arrow: %v", err)
+	}
+}
+
+func TestLoadAST(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(1 + 2)\n}\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	syncDatabase(root, dbPath)
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	target, err := parseASTTarget(root, "main.go:4:12")
+	if err != nil {
+		t.Fatalf("parse target: %v", err)
+	}
+	if target.FilePath != "main.go" || target.Line != 4 || target.Col != 12 {
+		t.Fatalf("unexpected target %+v", target)
+	}
+	target.FileID = -1
+	dump, err := loadAST(context.Background(), db, target, 0)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var out bytes.Buffer
+	writeAST(&out, dump)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"*ast.File",
+		"  Decls[0] *ast.FuncDecl",
+		"    Body *ast.BlockStmt",
+		"      List[0] *ast.ExprStmt",
+		"        X *ast.CallExpr",
+		"          Args[0] *ast.BinaryExpr + ",
+		"            X *ast.BasicLit 1 ",
+		"            Y *ast.BasicLit 2 ",
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("unexpected dump:\n%s", out.String())
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i+1], prefix) {
+			t.Fatalf("line %d = %q, want prefix %q", i+1, lines[i+1], prefix)
+		}
+	}
+
+	// The same node by file_id and ordinal, cut off below its own level.
+	byID, err := loadAST(context.Background(), db, astTarget{FileID: dump.FileID, Ordinal: dump.Node.Ordinal}, 1)
+	if err != nil {
+		t.Fatalf("load by ordinal: %v", err)
+	}
+	if byID.Node.Kind != "*ast.BinaryExpr" || len(byID.Node.Children) != 2 || len(byID.Ancestors) != 5 {
+		t.Fatalf("unexpected dump by ordinal: %+v", byID)
+	}
+	if _, err := loadAST(context.Background(), db, astTarget{FilePath: "main.go", FileID: -1, Line: 9}, 0); err == nil {
+		t.Fatal("expected an error for a line past the end of the file")
+	}
+}
+
+func TestMatchesFilters(t *testing.T) {
+	t.Parallel()
+
+	m := pattern.Match{FilePath: "internal/store/db.go", Package: "store"}
+	for _, tc := range []struct {
+		globs, packages []string
+		want            bool
+	}{
+		{nil, nil, true},
+		{[]string{"internal/store/*.go"}, nil, true},
+		{[]string{"internal/*.go"}, nil, false},
+		{[]string{"internal/..."}, nil, true},
+		{[]string{"internal/store/..."}, nil, true},
+		{[]string{"*/store/..."}, nil, true},
+		{[]string{"intern/..."}, nil, false},
+		{[]string{"cmd/...", "internal/store/db.go"}, nil, true},
+		{nil, []string{"main", "store"}, true},
+		{nil, []string{"main"}, false},
+		{[]string{"internal/..."}, []string{"main"}, false},
+	} {
+		if got := matchesFilters(m, tc.globs, tc.packages); got != tc.want {
+			t.Fatalf("globs=%v packages=%v: got %v, want %v", tc.globs, tc.packages, got, tc.want)
+		}
+	}
+}
+
+func TestCountFailed(t *testing.T) {
+	t.Parallel()
+
+	plans := []governance.FileFix{{FilePath: "a.go", Err: errors.New("file changed since it was indexed")}, {FilePath: "b.go"}}
+	edits := []governance.Violation{{FilePath: "a.go"}, {FilePath: "b.go"}, {FilePath: "a.go"}}
+	if got := countFailed(plans, edits); got != 2 {
+		t.Fatalf("countFailed = %d, want 2", got)
+	}
+}
+
+func TestRewriteRepo(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	file := filepath.Join(root, "main.go")
+	src := "package main\n\nfunc f(x int) int { return x }\n\nfunc g(x int) int { return x }\n\nfunc main() {\n\tprintln(f(f(1)))\n}\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	p, err := pattern.Parse("f($x)")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	opts := rewriteOptions{Repo: root, DBPath: filepath.Join(root, ".goast", "ast.db"), Pattern: p, Template: "g($x)", Diff: true}
+	read := func() string {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return string(b)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if _, err := rewriteRepo(context.Background(), &stdout, &stderr, opts); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "+\tprintln(g(f(1)))") || read() != src {
+		t.Fatalf("expected a diff and no writes, got:\n%s", stdout.String())
+	}
+
+	// The inner call overlaps the outer one, so it needs a second run.
+	opts.Diff = false
+	for i, want := range []string{"println(g(f(1)))", "println(g(g(1)))"} {
+		stdout.Reset()
+		stderr.Reset()
+		outcome, err := rewriteRepo(context.Background(), &stdout, &stderr, opts)
+		if err != nil || outcome.Failed {
+			t.Fatalf("run %d: %v %+v", i, err, outcome)
+		}
+		if !strings.Contains(read(), want) {
+			t.Fatalf("run %d: got\n%s", i, read())
+		}
+		if again := strings.Contains(stderr.String(), "run the rewrite again"); again != (i == 0) {
+			t.Fatalf("run %d: unexpected stderr:\n%s", i, stderr.String())
+		}
+	}
+}
+
+func TestWithoutFixed_RecordsStateAfterFix(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	fixable := governance.Violation{RuleID: "R1", FilePath: "a.go", Line: 1, Fix: &governance.Fix{Text: "x"}}
+	other := governance.Violation{RuleID: "R2", FilePath: "a.go", Line: 1}
+	stale := governance.Violation{RuleID: "R3", FilePath: "a.go", Line: 1, Fix: &governance.Fix{Text: "y"}}
+	report := governance.Report{Violations: []governance.Violation{fixable, other, stale}}
+	plans := []governance.FileFix{{FilePath: "a.go", Before: []byte("package a\n"), After: []byte("package a // fixed\n"), Applied: []governance.Violation{fixable}}}
+
+	var stdout, stderr bytes.Buffer
+	outcome := applyFixes(&stdout, &stderr, root, plans, report.Violations, false, true)
+	if len(outcome.Fixed) != 1 || outcome.Fixed[0].RuleID != "R1" {
+		t.Fatalf("unexpected fixed violations: %+v", outcome.Fixed)
+	}
+	recorded := withoutFixed(report, outcome.Fixed)
+	if len(recorded.Violations) != 2 || recorded.Violations[0].RuleID != "R2" || recorded.Violations[1].RuleID != "R3" {
+		t.Fatalf("history should hold only the violations left after the fix, got %+v", recorded.Violations)
+	}
+	if len(report.Violations) != 3 {
+		t.Fatal("withoutFixed must not change the report it was given")
+	}
+}
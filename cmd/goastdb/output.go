@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+var outputFormats = []string{"text", "json", "csv", "tsv", "ndjson", "markdown", "arrow"}
+
+const formatFlagUsage = "output format: text|json|csv|tsv|ndjson|markdown|arrow"
+
+func validateFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid -format %q (expected one of %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// writeTable renders t in any output format. Commands that wrap json output
+// in an envelope handle that format before calling it.
+func writeTable(w io.Writer, format string, t governance.Table) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	case "text":
+		if _, err := fmt.Fprintln(w, formatTable(t)); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "(%d rows)\n", len(t.Rows))
+		return err
+	case "csv":
+		return writeDelimited(w, ',', t)
+	case "tsv":
+		return writeDelimited(w, '\t', t)
+	case "ndjson":
+		return writeNDJSON(w, t)
+	case "markdown":
+		_, err := io.WriteString(w, formatMarkdown(t))
+		return err
+	case "arrow":
+		return writeArrow(w, t)
+	default:
+		return validateFormat(format)
+	}
+}
+
+func writeDelimited(w io.Writer, comma rune, t governance.Table) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for c := range t.Columns {
+			record[c] = ""
+			if c < len(row) && row[c] != nil {
+				record[c] = formatCell(row[c])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeNDJSON emits one JSON object per row, keeping keys in column order.
+func writeNDJSON(w io.Writer, t governance.Table) error {
+	keys := make([][]byte, len(t.Columns))
+	for i, col := range t.Columns {
+		k, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+	var b strings.Builder
+	for _, row := range t.Rows {
+		b.Reset()
+		b.WriteByte('{')
+		for c := range t.Columns {
+			if c > 0 {
+				b.WriteByte(',')
+			}
+			var cell any
+			if c < len(row) {
+				cell = row[c]
+			}
+			v, err := json.Marshal(cell)
+			if err != nil {
+				return fmt.Errorf("encode column %s: %w", t.Columns[c], err)
+			}
+			b.Write(keys[c])
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteString("}\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatMarkdown renders a GitHub-flavored Markdown table suitable for PR
+// descriptions. Pipes are escaped and newlines become <br>.
+func formatMarkdown(t governance.Table) string {
+	if len(t.Columns) == 0 {
+		return "_(no columns)_\n"
+	}
+	var b strings.Builder
+	b.WriteString("|")
+	for _, col := range t.Columns {
+		b.WriteString(" " + markdownCell(col) + " |")
+	}
+	b.WriteString("\n|")
+	for range t.Columns {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for _, row := range t.Rows {
+		b.WriteString("|")
+		for c := range t.Columns {
+			var cell any
+			if c < len(row) {
+				cell = row[c]
+			}
+			b.WriteString(" " + markdownCell(formatCell(cell)) + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// writeArrow writes t as an Arrow IPC stream. Column types come from the
+// DuckDB result metadata when available and are inferred from values otherwise.
+func writeArrow(w io.Writer, t governance.Table) error {
+	fields := make([]arrow.Field, len(t.Columns))
+	for i, col := range t.Columns {
+		fields[i] = arrow.Field{Name: col, Type: arrowType(columnType(t, i)), Nullable: true}
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	mem := memory.NewGoAllocator()
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	for _, row := range t.Rows {
+		for c := range t.Columns {
+			var cell any
+			if c < len(row) {
+				cell = row[c]
+			}
+			if err := appendArrowValue(rb.Field(c), cell); err != nil {
+				return fmt.Errorf("column %s: %w", t.Columns[c], err)
+			}
+		}
+	}
+	rec := rb.NewRecordBatch()
+	defer rec.Release()
+
+	iw := ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	if err := iw.Write(rec); err != nil {
+		_ = iw.Close()
+		return err
+	}
+	return iw.Close()
+}
+
+func columnType(t governance.Table, i int) string {
+	if i < len(t.Types) && t.Types[i] != "" {
+		return strings.ToUpper(t.Types[i])
+	}
+	for _, row := range t.Rows {
+		if i >= len(row) || row[i] == nil {
+			continue
+		}
+		switch row[i].(type) {
+		case bool:
+			return "BOOLEAN"
+		case int, int8, int16, int32, int64, uint8, uint16, uint32:
+			return "BIGINT"
+		case float32, float64:
+			return "DOUBLE"
+		case time.Time:
+			return "TIMESTAMP"
+		}
+		return "VARCHAR"
+	}
+	return "VARCHAR"
+}
+
+func arrowType(dbType string) arrow.DataType {
+	switch dbType {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "TINYINT":
+		return arrow.PrimitiveTypes.Int8
+	case "SMALLINT":
+		return arrow.PrimitiveTypes.Int16
+	case "INTEGER":
+		return arrow.PrimitiveTypes.Int32
+	case "BIGINT":
+		return arrow.PrimitiveTypes.Int64
+	case "UTINYINT":
+		return arrow.PrimitiveTypes.Uint8
+	case "USMALLINT":
+		return arrow.PrimitiveTypes.Uint16
+	case "UINTEGER":
+		return arrow.PrimitiveTypes.Uint32
+	case "UBIGINT":
+		return arrow.PrimitiveTypes.Uint64
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32
+	case "TIMESTAMP", "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE":
+		return arrow.FixedWidthTypes.Timestamp_us
+	default:
+		// VARCHAR and anything without a lossless mapping (HUGEINT, DECIMAL,
+		// lists, structs) is carried as its string form.
+		return arrow.BinaryTypes.String
+	}
+}
+
+func appendArrowValue(b array.Builder, v any) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch ab := b.(type) {
+	case *array.BooleanBuilder:
+		x, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %T", v)
+		}
+		ab.Append(x)
+	case *array.Int8Builder:
+		n, err := toInt64(v)
+		ab.Append(int8(n))
+		return err
+	case *array.Int16Builder:
+		n, err := toInt64(v)
+		ab.Append(int16(n))
+		return err
+	case *array.Int32Builder:
+		n, err := toInt64(v)
+		ab.Append(int32(n))
+		return err
+	case *array.Int64Builder:
+		n, err := toInt64(v)
+		ab.Append(n)
+		return err
+	case *array.Uint8Builder:
+		n, err := toInt64(v)
+		ab.Append(uint8(n))
+		return err
+	case *array.Uint16Builder:
+		n, err := toInt64(v)
+		ab.Append(uint16(n))
+		return err
+	case *array.Uint32Builder:
+		n, err := toInt64(v)
+		ab.Append(uint32(n))
+		return err
+	case *array.Uint64Builder:
+		if x, ok := v.(uint64); ok {
+			ab.Append(x)
+			return nil
+		}
+		n, err := toInt64(v)
+		ab.Append(uint64(n))
+		return err
+	case *array.Float32Builder:
+		f, err := toFloat64(v)
+		ab.Append(float32(f))
+		return err
+	case *array.Float64Builder:
+		f, err := toFloat64(v)
+		ab.Append(f)
+		return err
+	case *array.Date32Builder:
+		ts, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("expected time, got %T", v)
+		}
+		ab.Append(arrow.Date32FromTime(ts))
+	case *array.TimestampBuilder:
+		ts, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("expected time, got %T", v)
+		}
+		ab.Append(arrow.Timestamp(ts.UTC().UnixMicro()))
+	case *array.StringBuilder:
+		ab.Append(formatCell(v))
+	default:
+		return fmt.Errorf("unsupported arrow builder %T", b)
+	}
+	return nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint64:
+		return int64(x), nil
+	case *big.Int:
+		return x.Int64(), nil
+	case string:
+		return strconv.ParseInt(x, 10, 64)
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", v)
+	}
+}
+
+func toFloat64(v any) (float64, error) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	default:
+		n, err := toInt64(v)
+		return float64(n), err
+	}
+}
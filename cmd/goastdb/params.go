@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, e.g. `helper ID --param k=v`, and returns the positionals.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0, len(args))
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if rest[0] == "--" {
+			return append(positional, rest[1:]...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// parseParamValues splits repeated name=value flags into a map.
+func parseParamValues(raw []string) (map[string]string, error) {
+	out := make(map[string]string, len(raw))
+	for _, kv := range raw {
+		name, value, ok := strings.Cut(kv, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --param %q (expected name=value)", kv)
+		}
+		if _, dup := out[name]; dup {
+			return nil, fmt.Errorf("parameter %s given more than once", name)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// queryArgs builds bind arguments for a raw SQL query. Named parameters bind
+// to $name placeholders and may carry a type as name:int=80; positional
+// --arg values bind to ? or $1 placeholders. DuckDB cannot mix the two.
+func queryArgs(params, positional []string) ([]any, error) {
+	if len(params) > 0 && len(positional) > 0 {
+		return nil, fmt.Errorf("use either --param or --arg, not both")
+	}
+	args := make([]any, 0, len(params)+len(positional))
+	for _, a := range positional {
+		args = append(args, a)
+	}
+	values, err := parseParamValues(params)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range params {
+		key, _, _ := strings.Cut(kv, "=")
+		name, typ, _ := strings.Cut(strings.TrimSpace(key), ":")
+		v, err := explore.ConvertParam(typ, values[strings.TrimSpace(key)])
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		args = append(args, sql.Named(name, v))
+	}
+	return args, nil
+}
+
+func describeParams(params []explore.Param) string {
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		parts = append(parts, fmt.Sprintf("%s %s=%q", p.Name, p.Type, p.Default))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// selectHelpers merges the built-in helpers with those in <repo>/.goast/helpers
+// and dirs, restricted to ids when given.
+func selectHelpers(repo string, dirs, ids []string) ([]explore.Query, error) {
+	extra, err := explore.LoadHelpers(append([]string{filepath.Join(repo, explore.DefaultHelpersDir)}, dirs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return explore.SelectQueries(ids, extra...)
+}
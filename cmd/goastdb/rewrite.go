@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+	"github.com/Yacobolo/goastdb/pkg/astdb/pattern"
+)
+
+func runRewriteCommand(args []string) {
+	fs := flag.NewFlagSet("rewrite", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	diff := fs.Bool("diff", false, "print the rewrite as a unified diff without writing files")
+	var globs, packages stringList
+	fs.Var(&globs, "path", "only rewrite files whose repo-relative path matches this glob; dir/... matches a tree (repeatable)")
+	fs.Var(&packages, "pkg", "only rewrite files of this package name (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb rewrite [flags] <pattern> '->' <replacement>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Replaces every match of a Go pattern (see goastdb grep) with the replacement,")
+		fmt.Fprintln(os.Stderr, "substituting the source each $name or $*name matched. Quote the arrow so the")
+		fmt.Fprintln(os.Stderr, "shell does not read it as a redirection. Example:")
+		fmt.Fprintln(os.Stderr, "  goastdb rewrite 'errors.New(fmt.Sprintf($*args))' '->' 'fmt.Errorf($*args)'")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "After writing files the index is rebuilt from the whole repository, as it has")
+		fmt.Fprintln(os.Stderr, "no per-file update; rule files are not synced again.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	// "->" would parse as a flag, so the pattern and replacement around it are
+	// taken out before the flags are parsed.
+	arrow := -1
+	for i, a := range args {
+		if a == "->" {
+			arrow = i
+			break
+		}
+	}
+	if arrow < 1 || arrow+1 >= len(args) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	src, tmpl := args[arrow-1], args[arrow+1]
+	rest, err := parseInterspersed(fs, append(append([]string{}, args[:arrow-1]...), args[arrow+2:]...))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	for _, g := range globs {
+		if _, err := path.Match(strings.TrimSuffix(g, "/..."), ""); err != nil {
+			log.Fatalf("invalid --path %q: %v", g, err)
+		}
+	}
+	p, err := pattern.Parse(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := p.CheckTemplate(tmpl); err != nil {
+		log.Fatal(err)
+	}
+
+	outcome, err := rewriteRepo(context.Background(), os.Stdout, os.Stderr, rewriteOptions{
+		Repo:     *repo,
+		DBPath:   resolveDuckDBPath(*repo, *duckdbPath),
+		Pattern:  p,
+		Template: tmpl,
+		Globs:    globs,
+		Packages: packages,
+		Diff:     *diff,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outcome.Failed {
+		os.Exit(1)
+	}
+}
+
+type rewriteOptions struct {
+	Repo, DBPath    string
+	Pattern         *pattern.Pattern
+	Template        string
+	Globs, Packages []string
+	Diff            bool
+}
+
+// rewriteRepo replaces the matches of opts.Pattern selected by the filters,
+// or only prints the diff when opts.Diff is set.
+func rewriteRepo(ctx context.Context, stdout, stderr io.Writer, opts rewriteOptions) (fixOutcome, error) {
+	syncDatabase(opts.Repo, opts.DBPath)
+	db, err := sql.Open("duckdb", opts.DBPath)
+	if err != nil {
+		return fixOutcome{}, fmt.Errorf("open duckdb: %w", err)
+	}
+	matches, err := opts.Pattern.Find(ctx, db, opts.Repo)
+	_ = db.Close()
+	if err != nil {
+		return fixOutcome{}, err
+	}
+
+	edits := make([]governance.Violation, 0, len(matches))
+	for _, m := range matches {
+		if !matchesFilters(m, opts.Globs, opts.Packages) {
+			continue
+		}
+		edits = append(edits, governance.Violation{
+			RuleID:   "rewrite",
+			FilePath: m.FilePath,
+			Line:     m.Line,
+			Column:   m.Column,
+			Fix:      &governance.Fix{StartOffset: m.StartOffset, EndOffset: m.EndOffset, Text: opts.Pattern.Expand(opts.Template, m)},
+		})
+	}
+	plans, err := governance.NewRunner(opts.DBPath).PlanFixes(ctx, opts.Repo, edits)
+	if err != nil {
+		return fixOutcome{}, err
+	}
+	outcome := applyFixes(stdout, stderr, opts.Repo, plans, edits, opts.Diff, !opts.Diff)
+	if opts.Diff {
+		return outcome, nil
+	}
+	fmt.Fprintf(stderr, "rewrote %d of %d matches in %d files\n", outcome.Fixes, len(edits), outcome.Files)
+	if outcome.Files > 0 {
+		// Re-index so later queries see the rewritten code. Rule files were
+		// synced above and a rewrite does not touch them.
+		indexDatabase(opts.Repo, opts.DBPath)
+	}
+	if outcome.Fixes < len(edits)-countFailed(plans, edits) {
+		fmt.Fprintln(stderr, "some matches overlapped a rewritten one; run the rewrite again to apply them")
+	}
+	return outcome, nil
+}
+
+// matchesFilters reports whether m is in a file selected by --path and --pkg.
+func matchesFilters(m pattern.Match, globs, packages []string) bool {
+	if len(packages) > 0 {
+		found := false
+		for _, pkg := range packages {
+			found = found || pkg == m.Package
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(globs) == 0 {
+		return true
+	}
+	for _, g := range globs {
+		if dir, ok := strings.CutSuffix(g, "/..."); ok {
+			if ok, _ := path.Match(dir, m.FilePath); ok {
+				return true
+			}
+			for d := path.Dir(m.FilePath); d != "." && d != "/"; d = path.Dir(d) {
+				if ok, _ := path.Match(dir, d); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if ok, _ := path.Match(g, m.FilePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// countFailed counts the edits in files that could not be rewritten at all.
+// Such files have no Applied or Skipped entries, so edits are counted by file.
+func countFailed(plans []governance.FileFix, edits []governance.Violation) int {
+	failed := make(map[string]bool)
+	for _, p := range plans {
+		if p.Err != nil {
+			failed[p.FilePath] = true
+		}
+	}
+	n := 0
+	for _, e := range edits {
+		if failed[e.FilePath] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+func runRulesCommand(args []string) {
+	if len(args) == 0 {
+		printRulesUsage()
+		os.Exit(2)
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("rules "+sub, flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	var format, id, category, severity, description, sqlText, sqlFile, patternText, rulesDir *string
+	var disabled *bool
+	var ruleIDs stringList
+	switch sub {
+	case "list":
+		format = fs.String("format", "text", formatFlagUsage)
+	case "add":
+		id = fs.String("id", "", "rule ID (required)")
+		category = fs.String("category", "custom", "rule category")
+		severity = fs.String("severity", "warning", "rule severity: "+strings.Join(governance.Severities, "|"))
+		description = fs.String("description", "", "rule description (required)")
+		sqlText = fs.String("sql", "", "rule query returning file_path, symbol, detail, line")
+		sqlFile = fs.String("sql-file", "", "read the rule query from a file")
+		patternText = fs.String("pattern", "", "Go pattern to report instead of a query, e.g. 'panic($_)'")
+		disabled = fs.Bool("disabled", false, "add the rule disabled")
+	case "enable", "disable", "remove":
+	case "test":
+		rulesDir = fs.String("rules-dir", "", "rule files directory (default <repo>/"+governance.DefaultRulesDir+")")
+		fs.Var(&ruleIDs, "rule", "test only this rule ID (repeatable; required with a fixture dir)")
+	case "-h", "--help", "help":
+		printRulesUsage()
+		return
+	default:
+		log.Fatalf("unknown rules subcommand %q\n\n%s", sub, rulesUsageText())
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, rulesUsageText())
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if sub == "test" {
+		if len(rest) > 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		dir := *rulesDir
+		if dir == "" {
+			dir = filepath.Join(*repo, governance.DefaultRulesDir)
+		}
+		fixtures := ""
+		if len(rest) == 1 {
+			fixtures = rest[0]
+		}
+		if !runRuleTests(ctx, os.Stdout, *repo, dir, fixtures, ruleIDs) {
+			os.Exit(1)
+		}
+		return
+	}
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+	runner := governance.NewRunner(dbPath)
+
+	switch sub {
+	case "list":
+		if len(rest) != 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		rules, err := runner.ListRules(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printRuleList(*format, rules)
+	case "add":
+		if len(rest) != 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		query := *sqlText
+		if *sqlFile != "" {
+			if query != "" {
+				log.Fatal("use either --sql or --sql-file, not both")
+			}
+			raw, err := os.ReadFile(*sqlFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			query = string(raw)
+		}
+		rule := governance.Rule{
+			ID:          *id,
+			Category:    *category,
+			Severity:    *severity,
+			Description: *description,
+			QuerySQL:    query,
+			Pattern:     *patternText,
+			Enabled:     !*disabled,
+		}
+		warnings, err := runner.ValidateRuleSQL(ctx, rule)
+		if err != nil {
+			log.Fatalf("rule %s: %v", strings.TrimSpace(rule.ID), err)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: rule %s: %s\n", strings.TrimSpace(rule.ID), w)
+		}
+		if err := runner.UpsertRules(ctx, []governance.Rule{rule}); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("added rule %s\n", strings.TrimSpace(rule.ID))
+	case "enable", "disable":
+		if len(rest) == 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err := runner.SetRuleEnabled(ctx, sub == "enable", rest...); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%sd %s\n", sub, strings.Join(rest, ", "))
+	case "remove":
+		if len(rest) == 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err := runner.DeleteRule(ctx, rest...); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("removed %s\n", strings.Join(rest, ", "))
+	}
+}
+
+func printRuleList(format string, rules []governance.Rule) {
+	if err := validateFormat(format); err != nil {
+		log.Fatal(err)
+	}
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Rules []governance.Rule `json:"rules"`
+		}{Rules: rules}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	rows := make([][]any, 0, len(rules))
+	for _, r := range rules {
+		rows = append(rows, []any{r.ID, r.Category, r.Severity, r.Enabled, r.Description, r.Source})
+	}
+	t := governance.Table{Columns: []string{"id", "category", "severity", "enabled", "description", "source"}, Rows: rows}
+	if err := writeTable(os.Stdout, format, t); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runCheckCommand(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "text", formatFlagUsage+"|sarif")
+	failOn := fs.String("fail-on", "error", "exit 1 when a violation is at or above this severity ("+strings.Join(governance.Severities, "|")+"|none)")
+	baselinePath := fs.String("baseline", "", "report only violations missing from this baseline file")
+	noHistory := fs.Bool("no-history", false, "do not record this run in the violation history")
+	parallel := fs.Int("parallel", 0, "rules to run at once (default GOMAXPROCS)")
+	ruleTimeout := fs.Duration("rule-timeout", governance.DefaultRuleTimeout, "time limit per rule (0 disables)")
+	timing := fs.Bool("timing", false, "print per-rule elapsed time and row counts to stderr")
+	fix := fs.Bool("fix", false, "apply the fixes rules propose, then report what remains")
+	diff := fs.Bool("diff", false, "print the fixes rules propose as a unified diff without writing them")
+	writeBaseline := fs.Bool("write-baseline", false, "record current violations as the baseline (--baseline path, default <repo>/.goast/baseline.json)")
+	var ruleIDs stringList
+	fs.Var(&ruleIDs, "rule", "run only this enabled rule ID (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb check [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Runs governance rules and prints violations grouped by severity.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if len(fs.Args()) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	threshold, err := failThreshold(*failOn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *format != "sarif" {
+		if err := validateFormat(*format); err != nil {
+			log.Fatalf("%v; check also accepts sarif", err)
+		}
+	}
+
+	ctx := context.Background()
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+	runner := governance.NewRunner(dbPath)
+	if len(ruleIDs) > 0 {
+		if err := checkRuleIDs(ctx, runner, ruleIDs); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	timeout := *ruleTimeout
+	if timeout == 0 {
+		timeout = -1
+	}
+	report, err := runner.Check(ctx, governance.RunOptions{RuleIDs: ruleIDs, Parallelism: *parallel, RuleTimeout: timeout})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *timing {
+		writeRuleStats(os.Stderr, report.Stats)
+	}
+	violations := report.Violations
+	sortViolations(violations)
+	if *writeBaseline {
+		path := *baselinePath
+		if path == "" {
+			path = filepath.Join(*repo, ".goast", "baseline.json")
+		}
+		if err := governance.NewBaseline(violations).Write(path); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "wrote baseline of %d violations to %s\n", len(violations), path)
+		return
+	}
+	var fixed []governance.BaselineEntry
+	if *baselinePath != "" {
+		baseline, err := governance.ReadBaseline(*baselinePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		violations, fixed = baseline.Compare(violations)
+	}
+	fixFailed := false
+	var applied []governance.Violation
+	if *fix || *diff {
+		plans, err := runner.PlanFixes(ctx, *repo, violations)
+		if err != nil {
+			log.Fatal(err)
+		}
+		outcome := applyFixes(os.Stdout, os.Stderr, *repo, plans, violations, *diff, *fix)
+		violations, fixFailed, applied = outcome.Remaining, outcome.Failed, outcome.Fixed
+		// A preview prints only the diff.
+		if !*fix {
+			if fixFailed {
+				os.Exit(1)
+			}
+			return
+		}
+		fmt.Fprintf(os.Stderr, "applied %d fixes in %d files\n", outcome.Fixes, outcome.Files)
+	}
+	// Only full runs go into the history, as the tree stands after any fixes;
+	// a rule subset, a baseline write or a fix preview (which returned above)
+	// would make trends dip and spike for no change in the code.
+	if !*noHistory && len(ruleIDs) == 0 {
+		if _, err := runner.RecordRun(ctx, withoutFixed(report, applied), headCommit(*repo)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Violations          []governance.Violation          `json:"violations"`
+			Counts              map[string]int                  `json:"counts"`
+			Fixed               []governance.BaselineEntry      `json:"fixed,omitempty"`
+			Suppressed          int                             `json:"suppressed"`
+			SuppressionProblems []governance.SuppressionProblem `json:"suppression_problems,omitempty"`
+			RuleFailures        []governance.RuleStat           `json:"rule_failures,omitempty"`
+			RuleStats           []governance.RuleStat           `json:"rule_stats"`
+		}{
+			Violations:          violations,
+			Counts:              severityCounts(violations),
+			Fixed:               fixed,
+			Suppressed:          len(report.Suppressed),
+			SuppressionProblems: report.Problems,
+			RuleFailures:        report.Failures(),
+			RuleStats:           report.Stats,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	case "sarif":
+		rules, err := checkedRules(ctx, runner, ruleIDs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := governance.WriteSARIF(os.Stdout, rules, violations); err != nil {
+			log.Fatal(err)
+		}
+		writeSuppressionProblems(os.Stderr, report.Problems)
+		writeRuleFailures(os.Stderr, report.Failures())
+	case "text":
+		writeViolations(os.Stdout, violations)
+		if *baselinePath != "" {
+			writeFixed(os.Stdout, fixed)
+		}
+		writeSuppressionProblems(os.Stdout, report.Problems)
+		writeRuleFailures(os.Stdout, report.Failures())
+		if n := len(report.Suppressed); n > 0 {
+			fmt.Fprintf(os.Stdout, "%d suppressed by %s comments\n", n, governance.IgnoreDirective)
+		}
+	default:
+		if err := writeTable(os.Stdout, *format, violationTable(violations)); err != nil {
+			log.Fatal(err)
+		}
+		writeSuppressionProblems(os.Stderr, report.Problems)
+		writeRuleFailures(os.Stderr, report.Failures())
+	}
+
+	// A rule that could not run may hide violations, so it fails the check too.
+	if fixFailed || failing(violations, threshold) || (threshold > 0 && len(report.Failures()) > 0) {
+		os.Exit(1)
+	}
+}
+
+// failThreshold returns the severity rank that fails a check; "none" never fails.
+func failThreshold(severity string) (int, error) {
+	if strings.EqualFold(strings.TrimSpace(severity), "none") {
+		return 0, nil
+	}
+	rank := governance.SeverityRank(severity)
+	if rank == 0 {
+		return 0, fmt.Errorf("invalid -fail-on %q (expected %s|none)", severity, strings.Join(governance.Severities, "|"))
+	}
+	return rank, nil
+}
+
+func failing(violations []governance.Violation, threshold int) bool {
+	if threshold == 0 {
+		return false
+	}
+	for _, v := range violations {
+		if governance.SeverityRank(v.Severity) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRuleIDs rejects --rule values that name no rule, which would otherwise
+// pass silently.
+func checkRuleIDs(ctx context.Context, runner *governance.Runner, ids []string) error {
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		known[r.ID] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := known[id]; !ok {
+			return fmt.Errorf("unknown rule %q", id)
+		}
+	}
+	return nil
+}
+
+// checkedRules returns the enabled rules a check runs, for SARIF descriptors.
+func checkedRules(ctx context.Context, runner *governance.Runner, ids []string) ([]governance.Rule, error) {
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		selected[id] = struct{}{}
+	}
+	out := make([]governance.Rule, 0, len(rules))
+	for _, r := range rules {
+		if _, ok := selected[r.ID]; r.Enabled && (len(ids) == 0 || ok) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// sortViolations orders violations from most to least severe, then by location.
+func sortViolations(violations []governance.Violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if ra, rb := governance.SeverityRank(a.Severity), governance.SeverityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.RuleID < b.RuleID
+	})
+}
+
+func severityCounts(violations []governance.Violation) map[string]int {
+	counts := make(map[string]int, len(governance.Severities))
+	for _, s := range governance.Severities {
+		counts[s] = 0
+	}
+	for _, v := range violations {
+		counts[v.Severity]++
+	}
+	return counts
+}
+
+// writeViolations prints sorted violations in severity sections followed by a
+// summary line.
+func writeViolations(w io.Writer, violations []governance.Violation) {
+	if len(violations) == 0 {
+		fmt.Fprintln(w, "no violations")
+		return
+	}
+	counts := severityCounts(violations)
+	current := ""
+	for _, v := range violations {
+		if v.Severity != current {
+			if current != "" {
+				fmt.Fprintln(w)
+			}
+			current = v.Severity
+			fmt.Fprintf(w, "%s (%d)\n", strings.ToUpper(current), counts[current])
+		}
+		loc := v.FilePath
+		if v.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", v.FilePath, v.Line)
+		}
+		msg := v.Detail
+		if v.Symbol != "" {
+			msg = v.Symbol + ": " + v.Detail
+		}
+		fmt.Fprintf(w, "  %s  %s  %s\n", loc, v.RuleID, msg)
+	}
+
+	parts := make([]string, 0, len(governance.Severities))
+	for i := len(governance.Severities) - 1; i >= 0; i-- {
+		if s := governance.Severities[i]; counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	fmt.Fprintf(w, "\n%d violations (%s)\n", len(violations), strings.Join(parts, ", "))
+}
+
+// writeFixed lists baseline entries that no longer occur.
+func writeFixed(w io.Writer, fixed []governance.BaselineEntry) {
+	if len(fixed) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nFIXED SINCE BASELINE (%d)\n", len(fixed))
+	for _, e := range fixed {
+		msg := e.Detail
+		if e.Symbol != "" {
+			msg = e.Symbol + ": " + e.Detail
+		}
+		if e.Count > 1 {
+			msg = fmt.Sprintf("%s (x%d)", msg, e.Count)
+		}
+		fmt.Fprintf(w, "  %s  %s  %s\n", e.FilePath, e.RuleID, msg)
+	}
+}
+
+// writeSuppressionProblems lists ignore comments without a reason, naming
+// unknown rules, or no longer matching anything.
+func writeSuppressionProblems(w io.Writer, problems []governance.SuppressionProblem) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nSUPPRESSION PROBLEMS (%d)\n", len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(w, "  %s:%d  %s  %s\n", p.FilePath, p.Line, p.RuleID, p.Problem)
+	}
+}
+
+// writeRuleFailures lists rules that errored or timed out.
+func writeRuleFailures(w io.Writer, failures []governance.RuleStat) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nRULE FAILURES (%d)\n", len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(w, "  %s  %s\n", f.RuleID, f.Error)
+	}
+}
+
+// writeRuleStats prints rules slowest first.
+func writeRuleStats(w io.Writer, stats []governance.RuleStat) {
+	sorted := append([]governance.RuleStat(nil), stats...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Elapsed > sorted[j].Elapsed })
+	rows := make([][]any, 0, len(sorted))
+	for _, s := range sorted {
+		status := "ok"
+		if s.Failed() {
+			status = "failed"
+		}
+		rows = append(rows, []any{s.RuleID, s.Elapsed.Round(time.Microsecond).String(), s.Rows, status})
+	}
+	t := governance.Table{Columns: []string{"rule_id", "elapsed", "rows", "status"}, Rows: rows}
+	if err := writeTable(w, "text", t); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func rulesUsageText() string {
+	return strings.TrimSpace(`Usage:
+  goastdb rules [flags] list
+  goastdb rules [flags] add --id ID --description TEXT (--sql SQL | --sql-file PATH | --pattern PATTERN) [--severity S] [--category C] [--disabled]
+  goastdb rules [flags] enable <id>...
+  goastdb rules [flags] disable <id>...
+  goastdb rules [flags] remove <id>...
+  goastdb rules [flags] test [--rule ID]... [fixture-dir]
+
+Manages governance rules stored in the AST database.
+
+test runs rule files against fixtures without touching the database: the
+rules in <rules-dir>/NAME.yaml are checked against <rules-dir>/testdata/NAME,
+where each expected finding is marked with a // want "RULE_ID" comment on its
+line. With a fixture dir, the --rule IDs (rule files or built-ins) are
+checked against that directory instead.`)
+}
+
+func printRulesUsage() {
+	fmt.Fprintln(os.Stderr, rulesUsageText())
+}
+
+// violationTable lays violations out as rows for the tabular output formats.
+func violationTable(violations []governance.Violation) governance.Table {
+	t := governance.Table{
+		Columns: []string{"rule_id", "category", "severity", "file_path", "line", "column", "symbol", "detail"},
+		Types:   []string{"VARCHAR", "VARCHAR", "VARCHAR", "VARCHAR", "INTEGER", "INTEGER", "VARCHAR", "VARCHAR"},
+		Rows:    make([][]any, 0, len(violations)),
+	}
+	for _, v := range violations {
+		t.Rows = append(t.Rows, []any{v.RuleID, v.Category, v.Severity, v.FilePath, int32(v.Line), int32(v.Column), v.Symbol, v.Detail})
+	}
+	return t
+}
+
+// withoutFixed drops the violations a --fix run resolved from report.
+func withoutFixed(report governance.Report, fixed []governance.Violation) governance.Report {
+	if len(fixed) == 0 {
+		return report
+	}
+	done := make(map[*governance.Fix]struct{}, len(fixed))
+	for _, v := range fixed {
+		done[v.Fix] = struct{}{}
+	}
+	kept := make([]governance.Violation, 0, len(report.Violations))
+	for _, v := range report.Violations {
+		if _, ok := done[v.Fix]; ok && v.Fix != nil {
+			continue
+		}
+		kept = append(kept, v)
+	}
+	report.Violations = kept
+	return report
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance/ruletest"
+)
+
+// runRuleTests checks rule files against their fixtures and reports whether
+// every test passed. With fixtures set, the rules named by ids are checked
+// against that single directory.
+func runRuleTests(ctx context.Context, w io.Writer, repo, rulesDir, fixtures string, ids []string) bool {
+	rules, err := governance.LoadRuleFiles(repo, rulesDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	byID := make(map[string]governance.Rule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+
+	if fixtures != "" {
+		if len(ids) == 0 {
+			log.Fatal("rules test: --rule is required with a fixture dir")
+		}
+		selected := make([]governance.Rule, 0, len(ids))
+		for _, id := range ids {
+			rule, ok := byID[id]
+			if !ok {
+				// Not a rule file; Check resolves built-ins by ID.
+				rule = governance.Rule{ID: id}
+			}
+			selected = append(selected, rule)
+		}
+		return reportRuleTest(ctx, w, fixtures, fixtures, selected)
+	}
+
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			log.Fatalf("rules test: no rule file defines %s", id)
+		}
+		wanted[id] = struct{}{}
+	}
+	order := make([]string, 0)
+	bySource := make(map[string][]governance.Rule)
+	for _, rule := range rules {
+		if _, ok := wanted[rule.ID]; len(wanted) > 0 && !ok {
+			continue
+		}
+		if _, seen := bySource[rule.Source]; !seen {
+			order = append(order, rule.Source)
+		}
+		bySource[rule.Source] = append(bySource[rule.Source], rule)
+	}
+	if len(order) == 0 {
+		fmt.Fprintf(w, "no rule files in %s\n", rulesDir)
+		return true
+	}
+
+	ok := true
+	for _, source := range order {
+		base := strings.TrimSuffix(path.Base(source), path.Ext(source))
+		dir := filepath.Join(rulesDir, "testdata", base)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Fprintf(w, "?     %s  (no fixtures in %s)\n", source, dir)
+			continue
+		}
+		if !reportRuleTest(ctx, w, source, dir, bySource[source]) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func reportRuleTest(ctx context.Context, w io.Writer, name, dir string, rules []governance.Rule) bool {
+	res, err := ruletest.Check(ctx, dir, rules...)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL  %s\n      %v\n", name, err)
+		return false
+	}
+	if len(res.Mismatches) > 0 {
+		fmt.Fprintf(w, "FAIL  %s\n", name)
+		for _, m := range res.Mismatches {
+			fmt.Fprintf(w, "      %s\n", m)
+		}
+		return false
+	}
+	fmt.Fprintf(w, "ok    %s  (%d rules, %d findings)\n", name, len(rules), len(res.Violations))
+	return true
+}
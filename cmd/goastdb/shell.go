@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+const (
+	shellPrompt         = "goastdb> "
+	shellContinuePrompt = "   ...> "
+	shellHistoryLimit   = 1000
+)
+
+type shell struct {
+	ctx         context.Context
+	repo        string
+	duckdbPath  string
+	historyPath string
+	helperDirs  []string
+
+	db     *sql.DB
+	runner *governance.Runner
+
+	in          *bufio.Scanner
+	out         io.Writer
+	interactive bool
+
+	format  string
+	timer   bool
+	pager   bool
+	history []string
+}
+
+func runShellCommand(args []string) {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	format := fs.String("format", "text", formatFlagUsage)
+	var helperDirs stringList
+	fs.Var(&helperDirs, "helpers-dir", "extra directory of *.sql helpers, besides <repo>/.goast/helpers (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb shell [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Starts an interactive SQL shell over one open AST database connection.")
+		fmt.Fprintln(os.Stderr, "Statements end with ';'. Type .help for shell commands.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+
+	dbPath := resolveDuckDBPath(*repo, *duckdbPath)
+	syncDatabase(*repo, dbPath)
+
+	sh := &shell{
+		ctx:         context.Background(),
+		repo:        *repo,
+		duckdbPath:  dbPath,
+		historyPath: filepath.Join(filepath.Dir(dbPath), "shell_history"),
+		helperDirs:  helperDirs,
+		in:          bufio.NewScanner(os.Stdin),
+		out:         os.Stdout,
+		interactive: isTerminal(os.Stdin) && isTerminal(os.Stdout),
+		format:      *format,
+		pager:       true,
+	}
+	if err := sh.open(); err != nil {
+		log.Fatal(err)
+	}
+	defer sh.close()
+	sh.loadHistory()
+	if err := sh.run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (sh *shell) open() error {
+	db, err := sql.Open("duckdb", sh.duckdbPath)
+	if err != nil {
+		return fmt.Errorf("open duckdb: %w", err)
+	}
+	sh.db = db
+	sh.runner = governance.NewRunnerFromDB(db)
+	return nil
+}
+
+func (sh *shell) close() {
+	if sh.db != nil {
+		_ = sh.db.Close()
+		sh.db = nil
+	}
+}
+
+func (sh *shell) run() error {
+	sh.in.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if sh.interactive {
+		fmt.Fprintln(sh.out, "goastdb shell: statements end with ';', .help for commands, .quit to exit")
+	}
+	var stmt strings.Builder
+	for {
+		if sh.interactive {
+			if stmt.Len() == 0 {
+				fmt.Fprint(sh.out, shellPrompt)
+			} else {
+				fmt.Fprint(sh.out, shellContinuePrompt)
+			}
+		}
+		if !sh.in.Scan() {
+			break
+		}
+		line := sh.in.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if stmt.Len() == 0 {
+			switch {
+			case trimmed == "":
+				continue
+			case strings.HasPrefix(trimmed, "."):
+				quit, err := sh.command(trimmed)
+				if err != nil {
+					fmt.Fprintf(sh.out, "Error: %v\n", err)
+				}
+				if quit {
+					return nil
+				}
+				continue
+			case strings.HasPrefix(trimmed, "!"):
+				if err := sh.rerun(strings.TrimPrefix(trimmed, "!")); err != nil {
+					fmt.Fprintf(sh.out, "Error: %v\n", err)
+				}
+				continue
+			}
+		}
+
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if !statementComplete(stmt.String()) {
+			continue
+		}
+		query := strings.TrimSpace(stmt.String())
+		stmt.Reset()
+		sh.remember(query)
+		if err := sh.execute(query); err != nil {
+			fmt.Fprintf(sh.out, "Error: %v\n", err)
+		}
+	}
+	if err := sh.in.Err(); err != nil {
+		return err
+	}
+	// Run a trailing statement that was not terminated before EOF.
+	if query := strings.TrimSpace(stmt.String()); query != "" {
+		sh.remember(query)
+		if err := sh.execute(query); err != nil {
+			fmt.Fprintf(sh.out, "Error: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// statementComplete reports whether sql ends with a ';' that sits outside
+// string literals, quoted identifiers and comments, so a line ending in ';'
+// inside a multi-line literal or after "--" does not end the statement.
+func statementComplete(sql string) bool {
+	complete := false
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"':
+			// A doubled quote is an escaped one, so skipping to the next quote
+			// and rescanning from there handles it.
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+			complete = false
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return complete
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += end + 3
+		case c == ';':
+			complete = true
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			complete = false
+		}
+	}
+	return complete
+}
+
+func (sh *shell) execute(query string, args ...any) error {
+	start := time.Now()
+	table, err := sh.runner.QueryTable(sh.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	if err := sh.render(table); err != nil {
+		return err
+	}
+	if sh.timer {
+		fmt.Fprintf(sh.out, "Run Time: %s\n", elapsed.Round(time.Microsecond))
+	}
+	return nil
+}
+
+func (sh *shell) render(t governance.Table) error {
+	var buf bytes.Buffer
+	if err := writeTable(&buf, sh.format, t); err != nil {
+		return err
+	}
+	return sh.page(buf.Bytes())
+}
+
+// page sends output longer than one screen through $PAGER when the shell is
+// attached to a terminal, and writes it directly otherwise.
+func (sh *shell) page(b []byte) error {
+	if !sh.interactive || !sh.pager || bytes.Count(b, []byte("\n")) < terminalLines() {
+		_, err := sh.out.Write(b)
+		return err
+	}
+	pager := strings.TrimSpace(os.Getenv("PAGER"))
+	if pager == "" {
+		pager = "less -FRX"
+	}
+	fields := strings.Fields(pager)
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		_, werr := sh.out.Write(b)
+		return werr
+	}
+	return nil
+}
+
+func terminalLines() int {
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		return n
+	}
+	return 40
+}
+
+func (sh *shell) command(line string) (bool, error) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	switch name {
+	case ".quit", ".exit":
+		return true, nil
+	case ".help":
+		fmt.Fprintln(sh.out, shellHelpText())
+	case ".tables":
+		return false, sh.execute(`
+SELECT table_name AS name, 'table' AS type FROM duckdb_tables() WHERE schema_name = 'main'
+UNION ALL
+SELECT view_name, 'view' FROM duckdb_views() WHERE schema_name = 'main' AND NOT internal
+ORDER BY name`)
+	case ".schema":
+		query := `
+SELECT sql FROM duckdb_tables() WHERE schema_name = 'main' AND ($1 = '' OR table_name = $1)
+UNION ALL
+SELECT sql FROM duckdb_views() WHERE schema_name = 'main' AND NOT internal AND ($1 = '' OR view_name = $1)`
+		table, err := sh.runner.QueryTable(sh.ctx, query, strings.Join(args, " "))
+		if err != nil {
+			return false, err
+		}
+		if len(table.Rows) == 0 {
+			return false, fmt.Errorf("no table or view named %q", strings.Join(args, " "))
+		}
+		for _, row := range table.Rows {
+			fmt.Fprintf(sh.out, "%s\n", formatCell(row[0]))
+		}
+	case ".helpers":
+		queries, err := selectHelpers(sh.repo, sh.helperDirs, nil)
+		if err != nil {
+			return false, err
+		}
+		rows := make([][]any, 0, len(queries))
+		for _, q := range queries {
+			rows = append(rows, []any{q.ID, q.Description, q.Source})
+		}
+		return false, sh.render(governance.Table{Columns: []string{"id", "description", "source"}, Rows: rows})
+	case ".run":
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: .run <HELPER_ID> [name=value ...]")
+		}
+		helpers, err := selectHelpers(sh.repo, sh.helperDirs, args[:1])
+		if err != nil {
+			return false, err
+		}
+		values, err := parseParamValues(args[1:])
+		if err != nil {
+			return false, err
+		}
+		bindArgs, err := helpers[0].Bind(values)
+		if err != nil {
+			return false, err
+		}
+		return false, sh.execute(helpers[0].SQL, bindArgs...)
+	case ".format":
+		if len(args) != 1 {
+			fmt.Fprintf(sh.out, "format: %s\n", sh.format)
+			return false, nil
+		}
+		if err := validateFormat(args[0]); err != nil {
+			return false, err
+		}
+		sh.format = args[0]
+	case ".timer":
+		on, err := parseOnOff(args)
+		if err != nil {
+			return false, err
+		}
+		sh.timer = on
+	case ".pager":
+		on, err := parseOnOff(args)
+		if err != nil {
+			return false, err
+		}
+		sh.pager = on
+	case ".history":
+		for i, h := range sh.history {
+			fmt.Fprintf(sh.out, "%5d  %s\n", i+1, strings.ReplaceAll(h, "\n", " "))
+		}
+	case ".reindex":
+		return false, sh.reindex()
+	default:
+		return false, fmt.Errorf("unknown command %s (try .help)", name)
+	}
+	return false, nil
+}
+
+func parseOnOff(args []string) (bool, error) {
+	if len(args) == 1 {
+		switch args[0] {
+		case "on":
+			return true, nil
+		case "off":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("expected on or off")
+}
+
+// reindex closes the shell's connection so the indexer can take the database
+// lock, forces a rebuild, and reopens the connection.
+func (sh *shell) reindex() error {
+	sh.close()
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = sh.repo
+	opts.DuckDBPath = sh.duckdbPath
+	opts.Mode = "build"
+	opts.ForceRebuild = true
+	opts.QueryBench = false
+	res, runErr := astdb.Run(sh.ctx, opts)
+	if runErr == nil {
+		runErr = governance.NewRunner(sh.duckdbPath).SyncRuleFiles(sh.ctx, sh.repo)
+	}
+	if err := sh.open(); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if res.Sync.RolledBack {
+		return fmt.Errorf("rebuild failed, kept the previous index: %s", res.Sync.RollbackError)
+	}
+	fmt.Fprintf(sh.out, "reindexed %d files (%d nodes) in %s\n", res.Sync.FilesCount, res.Sync.NodesCount, (res.Sync.ParseElapsed + res.Sync.LoadElapsed).Round(time.Millisecond))
+	return nil
+}
+
+func (sh *shell) rerun(arg string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(sh.history) {
+		return fmt.Errorf("no history entry %q", arg)
+	}
+	query := sh.history[n-1]
+	fmt.Fprintln(sh.out, query)
+	sh.remember(query)
+	return sh.execute(query)
+}
+
+// loadHistory reads the on-disk history, rewriting the file when it holds
+// more than shellHistoryLimit entries.
+func (sh *shell) loadHistory() {
+	b, err := os.ReadFile(sh.historyPath)
+	if err != nil {
+		return
+	}
+	for _, entry := range strings.Split(string(b), "\x00\n") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			sh.history = append(sh.history, entry)
+		}
+	}
+	if len(sh.history) > shellHistoryLimit {
+		sh.history = sh.history[len(sh.history)-shellHistoryLimit:]
+		sh.saveHistory()
+	}
+}
+
+// remember appends a statement to the in-memory and on-disk history. Entries
+// are NUL-terminated so multi-line statements survive a round trip.
+func (sh *shell) remember(query string) {
+	sh.history = append(sh.history, query)
+	if len(sh.history) > shellHistoryLimit {
+		sh.history = sh.history[len(sh.history)-shellHistoryLimit:]
+		sh.saveHistory()
+		return
+	}
+	if sh.historyPath == "" {
+		return
+	}
+	f, err := os.OpenFile(sh.historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_, _ = f.WriteString(query + "\x00\n")
+}
+
+// saveHistory replaces the on-disk history with the in-memory one.
+func (sh *shell) saveHistory() {
+	if sh.historyPath == "" {
+		return
+	}
+	var b strings.Builder
+	for _, entry := range sh.history {
+		b.WriteString(entry + "\x00\n")
+	}
+	_ = os.WriteFile(sh.historyPath, []byte(b.String()), 0o600)
+}
+
+func shellHelpText() string {
+	return strings.TrimSpace(`
+SQL statements may span lines and end with ';'.
+
+.tables               list tables and views
+.schema [name]        show CREATE statements (all, or one table/view)
+.helpers              list helper queries
+.run <ID> [k=v ...]   run a helper query with optional parameters
+.format [fmt]         show or set output format (text|json|csv|tsv|ndjson|markdown|arrow)
+.timer on|off         print query run time
+.pager on|off         page long results through $PAGER (default less -FRX)
+.history              list previous statements
+!<n>                  re-run history entry n
+.reindex              force a rebuild of the index and reconnect
+.quit                 exit`)
+}
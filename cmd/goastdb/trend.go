@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+func runTrendCommand(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	repo := fs.String("repo", ".", "repository root to scan")
+	duckdbPath := fs.String("duckdb", "", "duckdb output path (default <repo>/.goast/ast.db)")
+	by := fs.String("by", "rule", "group counts by rule or category")
+	runs := fs.Int("runs", 10, "number of most recent check runs to show")
+	format := fs.String("format", "text", formatFlagUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goastdb trend [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Shows violation counts of recorded check runs, oldest first, per rule or category.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if len(fs.Args()) != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+
+	// History lives outside the index, so there is nothing to sync.
+	runner := governance.NewRunner(resolveDuckDBPath(*repo, *duckdbPath))
+	t, err := runner.Trend(context.Background(), governance.TrendOptions{By: *by, Runs: *runs})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeTable(os.Stdout, *format, t); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// headCommit returns the commit checked out in repo, or "" outside git.
+func headCommit(repo string) string {
+	out, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
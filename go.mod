@@ -2,10 +2,13 @@ module github.com/Yacobolo/goastdb
 
 go 1.25.7
 
-require github.com/duckdb/duckdb-go/v2 v2.5.5
+require (
+	github.com/apache/arrow-go/v18 v18.5.1
+	github.com/duckdb/duckdb-go/v2 v2.5.5
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/apache/arrow-go/v18 v18.5.1 // indirect
 	github.com/duckdb/duckdb-go-bindings v0.3.3 // indirect
 	github.com/duckdb/duckdb-go-bindings/lib/darwin-amd64 v0.3.3 // indirect
 	github.com/duckdb/duckdb-go-bindings/lib/darwin-arm64 v0.3.3 // indirect
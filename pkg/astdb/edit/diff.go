@@ -0,0 +1,171 @@
+package edit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type lineOp struct {
+	kind       opKind
+	text       string
+	aLine, bLn int // 0-based line in the old and new text
+}
+
+// Unified returns a unified diff between before and after, labelled a/path
+// and b/path, or "" when they are equal.
+func Unified(path string, before, after []byte) string {
+	if string(before) == string(after) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(before)), splitLines(string(after)))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", path, path)
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are close.
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		lo := max(first-contextLines, start)
+		hi := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				hi = i
+			} else if i-hi > 2*contextLines {
+				break
+			}
+		}
+		hi = min(hi+contextLines+1, len(ops))
+		writeHunk(&sb, ops[lo:hi])
+		start = hi
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []lineOp) {
+	aStart, bStart := -1, -1
+	aCount, bCount := 0, 0
+	for _, op := range ops {
+		if op.kind != opInsert {
+			if aStart < 0 {
+				aStart = op.aLine
+			}
+			aCount++
+		}
+		if op.kind != opDelete {
+			if bStart < 0 {
+				bStart = op.bLn
+			}
+			bCount++
+		}
+	}
+	// An empty side is reported at the line before the hunk, per the format.
+	if aStart < 0 {
+		aStart = ops[0].aLine - 1
+	}
+	if bStart < 0 {
+		bStart = ops[0].bLn - 1
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, op := range ops {
+		sb.WriteByte(byte(op.kind))
+		sb.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s after each newline, keeping the newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm.
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	trace := make([][]int, 0)
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	ops := make([]lineOp, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: opEqual, text: a[x], aLine: x, bLn: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, lineOp{kind: opInsert, text: b[y], aLine: x, bLn: y})
+			} else {
+				x--
+				ops = append(ops, lineOp{kind: opDelete, text: a[x], aLine: x, bLn: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
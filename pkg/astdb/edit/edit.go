@@ -0,0 +1,78 @@
+// Package edit applies byte-offset text edits to source files and renders
+// the result as a unified diff.
+package edit
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Edit replaces src[Start:End] with Text. An insertion has Start == End.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// OverlapError reports two edits that touch the same bytes.
+type OverlapError struct {
+	First, Second Edit
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("edit [%d,%d) overlaps edit [%d,%d)", e.Second.Start, e.Second.End, e.First.Start, e.First.End)
+}
+
+// Validate checks that edits fit in a source of size n and do not overlap.
+// Identical edits are allowed; Apply makes them once.
+func Validate(n int, edits []Edit) error {
+	sorted := sortedEdits(edits)
+	for i, e := range sorted {
+		if e.Start < 0 || e.End < e.Start || e.End > n {
+			return fmt.Errorf("edit [%d,%d) is outside the %d-byte source", e.Start, e.End, n)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := sorted[i-1]
+		if e == prev {
+			continue
+		}
+		// Two insertions at one offset would apply in an arbitrary order.
+		if e.Start < prev.End || (e.Start == prev.Start && e.Start == e.End) {
+			return &OverlapError{First: prev, Second: e}
+		}
+	}
+	return nil
+}
+
+// Apply returns src with edits applied. It fails on overlapping edits.
+func Apply(src []byte, edits []Edit) ([]byte, error) {
+	if err := Validate(len(src), edits); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(src))
+	last := 0
+	var prev *Edit
+	for _, e := range sortedEdits(edits) {
+		if prev != nil && e == *prev {
+			continue
+		}
+		out = append(out, src[last:e.Start]...)
+		out = append(out, e.Text...)
+		last = e.End
+		prev = &e
+	}
+	return append(out, src[last:]...), nil
+}
+
+func sortedEdits(edits []Edit) []Edit {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		return sorted[i].End < sorted[j].End
+	})
+	return sorted
+}
@@ -0,0 +1,82 @@
+package edit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	src := []byte("var x interface{} = 1\n")
+	got, err := Apply(src, []Edit{
+		{Start: 20, End: 21, Text: "2"},
+		{Start: 6, End: 17, Text: "any"},
+		{Start: 6, End: 17, Text: "any"}, // duplicates apply once
+		{Start: 0, End: 0, Text: "// x\n"},
+	})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if want := "// x\nvar x any = 2\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestApply_RejectsOverlapsAndOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	src := []byte("0123456789")
+	cases := map[string][]Edit{
+		"overlap":          {{Start: 2, End: 5, Text: "a"}, {Start: 4, End: 6, Text: "b"}},
+		"same insertion":   {{Start: 3, End: 3, Text: "a"}, {Start: 3, End: 3, Text: "b"}},
+		"different change": {{Start: 3, End: 4, Text: "a"}, {Start: 3, End: 4, Text: "b"}},
+	}
+	for name, edits := range cases {
+		var overlap *OverlapError
+		if _, err := Apply(src, edits); !errors.As(err, &overlap) {
+			t.Errorf("%s: expected an OverlapError, got %v", name, err)
+		}
+	}
+	if _, err := Apply(src, []Edit{{Start: 8, End: 11}}); err == nil {
+		t.Fatal("expected an out-of-range edit to fail")
+	}
+	// Adjacent edits do not overlap.
+	if got, err := Apply(src, []Edit{{Start: 2, End: 4, Text: "x"}, {Start: 4, End: 4, Text: "y"}}); err != nil || string(got) != "01xy456789" {
+		t.Fatalf("adjacent edits: got %q, %v", got, err)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nn\nend"
+	want := `--- a/x.go
++++ b/x.go
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,5 +10,5 @@
+ j
+ k
+ l
+-m
+ n
++end
+\ No newline at end of file
+`
+	if got := Unified("x.go", []byte(before), []byte(after)); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified("x.go", []byte(before), []byte(before)); got != "" {
+		t.Fatalf("expected no diff for equal input, got %q", got)
+	}
+	if got, want := Unified("x.go", nil, []byte("a\n")), "--- a/x.go\n+++ b/x.go\n@@ -0,0 +1 @@\n+a\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
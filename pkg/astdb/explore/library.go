@@ -0,0 +1,216 @@
+package explore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceBuiltin is the Source reported for helpers compiled into goastdb.
+const SourceBuiltin = "builtin"
+
+// DefaultHelpersDir is the repository-relative directory scanned for user helpers.
+const DefaultHelpersDir = ".goast/helpers"
+
+// helperMeta is the sidecar (.yaml, .yml, .json) form of a helper's metadata.
+type helperMeta struct {
+	ID          string   `json:"id" yaml:"id"`
+	Description string   `json:"description" yaml:"description"`
+	Tags        []string `json:"tags" yaml:"tags"`
+	Params      []Param  `json:"params" yaml:"params"`
+}
+
+// LoadHelpers reads every *.sql file in dirs as a helper query. Metadata comes
+// from a sidecar file with the same base name or, failing that, from leading
+// "-- key: value" comment lines:
+//
+//	-- id: HANDLERS_WITHOUT_CONTEXT
+//	-- description: HTTP handlers that ignore the request context
+//	-- tags: http, context
+//	-- param: min_lines int 20 minimum handler length
+//
+// Directories that do not exist are skipped. The ID defaults to the upper-cased
+// file name.
+func LoadHelpers(dirs ...string) ([]Query, error) {
+	out := make([]Query, 0)
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("scan helpers dir %s: %w", dir, err)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			q, err := loadHelperFile(path)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+func loadHelperFile(path string) (Query, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Query{}, fmt.Errorf("read helper: %w", err)
+	}
+	body := string(raw)
+	meta, found, err := readSidecar(strings.TrimSuffix(path, filepath.Ext(path)))
+	if err != nil {
+		return Query{}, err
+	}
+	if !found {
+		meta, err = parseHeader(body)
+		if err != nil {
+			return Query{}, fmt.Errorf("helper %s: %w", path, err)
+		}
+	}
+
+	q := Query{
+		ID:          strings.TrimSpace(meta.ID),
+		Description: strings.TrimSpace(meta.Description),
+		SQL:         body,
+		Params:      meta.Params,
+		Tags:        meta.Tags,
+		Source:      filepath.ToSlash(path),
+	}
+	if q.ID == "" {
+		q.ID = helperIDFromFile(path)
+	}
+	if strings.TrimSpace(stripComments(body)) == "" {
+		return Query{}, fmt.Errorf("helper %s: empty sql", path)
+	}
+	seen := make(map[string]struct{}, len(q.Params))
+	for _, p := range q.Params {
+		if strings.TrimSpace(p.Name) == "" {
+			return Query{}, fmt.Errorf("helper %s: parameter without a name", path)
+		}
+		if _, dup := seen[p.Name]; dup {
+			return Query{}, fmt.Errorf("helper %s: parameter %s declared twice", path, p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		if _, err := ConvertParam(p.Type, p.Default); err != nil {
+			return Query{}, fmt.Errorf("helper %s: parameter %s default: %w", path, p.Name, err)
+		}
+	}
+	return q, nil
+}
+
+func readSidecar(base string) (helperMeta, bool, error) {
+	var meta helperMeta
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		raw, err := os.ReadFile(base + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return meta, false, fmt.Errorf("read helper metadata: %w", err)
+		}
+		if ext == ".json" {
+			err = json.Unmarshal(raw, &meta)
+		} else {
+			err = yaml.Unmarshal(raw, &meta)
+		}
+		if err != nil {
+			return meta, false, fmt.Errorf("parse helper metadata %s: %w", base+ext, err)
+		}
+		return meta, true, nil
+	}
+	return meta, false, nil
+}
+
+// parseHeader reads metadata from the comment lines that open a helper file.
+func parseHeader(body string) (helperMeta, error) {
+	var meta helperMeta
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "--")), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "id":
+			meta.ID = value
+		case "description":
+			meta.Description = value
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					meta.Tags = append(meta.Tags, tag)
+				}
+			}
+		case "param":
+			p, err := parseParamLine(value)
+			if err != nil {
+				return meta, err
+			}
+			meta.Params = append(meta.Params, p)
+		}
+	}
+	return meta, nil
+}
+
+// parseParamLine parses "name type default description...". A quoted default
+// ("" or "a b") may contain spaces or be empty.
+func parseParamLine(value string) (Param, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return Param{}, fmt.Errorf("invalid param %q (expected: name type default [description])", value)
+	}
+	p := Param{Name: fields[0], Type: fields[1]}
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(value, fields[0])), fields[1]))
+	if strings.HasPrefix(rest, `"`) {
+		end := strings.Index(rest[1:], `"`)
+		if end < 0 {
+			return Param{}, fmt.Errorf("invalid param %q: unterminated default", value)
+		}
+		def, err := strconv.Unquote(rest[:end+2])
+		if err != nil {
+			return Param{}, fmt.Errorf("invalid param %q: %w", value, err)
+		}
+		p.Default = def
+		p.Description = strings.TrimSpace(rest[end+2:])
+		return p, nil
+	}
+	def, desc, _ := strings.Cut(rest, " ")
+	p.Default = def
+	p.Description = strings.TrimSpace(desc)
+	return p, nil
+}
+
+func stripComments(body string) string {
+	lines := strings.Split(body, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func helperIDFromFile(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,88 @@
+package explore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadHelpers_HeaderAndSidecar(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeHelperFile(t, filepath.Join(dir, "long_funcs.sql"), `-- id: LONG_EXPORTED
+-- description: Exported functions above a threshold
+-- tags: size, api
+-- param: min_lines int 40 minimum line span
+-- param: prefix string "" path prefix
+SELECT path, name FROM functions WHERE line_span >= $min_lines AND starts_with(path, $prefix)
+`)
+	writeHelperFile(t, filepath.Join(dir, "method-count.sql"), "SELECT COUNT(*) FROM methods WHERE receiver = $recv\n")
+	writeHelperFile(t, filepath.Join(dir, "method-count.yaml"), "description: methods per receiver\ntags: [api]\nparams:\n  - {name: recv, type: string, default: Server}\n")
+
+	helpers, err := LoadHelpers(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("load helpers: %v", err)
+	}
+	if len(helpers) != 2 {
+		t.Fatalf("expected 2 helpers, got %d", len(helpers))
+	}
+
+	long := helpers[0]
+	if long.ID != "LONG_EXPORTED" || long.Description != "Exported functions above a threshold" {
+		t.Fatalf("unexpected header metadata: %+v", long)
+	}
+	if strings.Join(long.Tags, ",") != "size,api" || len(long.Params) != 2 {
+		t.Fatalf("unexpected tags/params: %+v", long)
+	}
+	if p := long.Params[0]; p.Name != "min_lines" || p.Type != "int" || p.Default != "40" || p.Description != "minimum line span" {
+		t.Fatalf("unexpected param: %+v", p)
+	}
+	if p := long.Params[1]; p.Name != "prefix" || p.Default != "" || p.Description != "path prefix" {
+		t.Fatalf("unexpected quoted param: %+v", p)
+	}
+
+	methods := helpers[1]
+	if methods.ID != "METHOD_COUNT" || methods.Description != "methods per receiver" || len(methods.Params) != 1 || methods.Params[0].Default != "Server" {
+		t.Fatalf("unexpected sidecar metadata: %+v", methods)
+	}
+	if !strings.HasSuffix(methods.Source, "method-count.sql") {
+		t.Fatalf("expected source path, got %q", methods.Source)
+	}
+
+	all, err := SelectQueries(nil, helpers...)
+	if err != nil {
+		t.Fatalf("select queries: %v", err)
+	}
+	if len(all) != len(DefaultQueries())+2 || all[0].Source != SourceBuiltin {
+		t.Fatalf("unexpected merged helpers: %d", len(all))
+	}
+}
+
+func TestLoadHelpers_InvalidDefault(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeHelperFile(t, filepath.Join(dir, "bad.sql"), "-- param: n int many\nSELECT $n\n")
+	if _, err := LoadHelpers(dir); err == nil {
+		t.Fatal("expected error for invalid int default")
+	}
+}
+
+func TestSelectQueries_Collision(t *testing.T) {
+	t.Parallel()
+
+	dup := Query{ID: "PARSE_ERRORS", SQL: "SELECT 1", Source: ".goast/helpers/parse_errors.sql"}
+	_, err := SelectQueries(nil, dup)
+	if err == nil || !strings.Contains(err.Error(), "builtin") || !strings.Contains(err.Error(), dup.Source) {
+		t.Fatalf("expected collision error naming both sources, got %v", err)
+	}
+}
+
+func writeHelperFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
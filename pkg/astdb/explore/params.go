@@ -0,0 +1,97 @@
+package explore
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default"`
+	Description string `json:"description,omitempty"`
+}
+
+func limitParam(def int) Param {
+	return Param{Name: "limit", Type: "int", Default: strconv.Itoa(def), Description: "maximum rows returned"}
+}
+
+func pathPrefixParam() Param {
+	return Param{Name: "path_prefix", Type: "string", Default: "", Description: "only include files whose path starts with this prefix"}
+}
+
+// Bind resolves values against the declared parameters, filling defaults, and
+// returns them as named arguments for the query's $name placeholders.
+func (q Query) Bind(values map[string]string) ([]any, error) {
+	declared := make(map[string]Param, len(q.Params))
+	for _, p := range q.Params {
+		declared[p.Name] = p
+	}
+	unknown := make([]string, 0)
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("query %s: unknown parameter(s) %s (declared: %s)", q.ID, strings.Join(unknown, ", "), q.paramNames())
+	}
+
+	args := make([]any, 0, len(q.Params))
+	for _, p := range q.Params {
+		raw, ok := values[p.Name]
+		if !ok {
+			raw = p.Default
+		}
+		v, err := ConvertParam(p.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: parameter %s: %w", q.ID, p.Name, err)
+		}
+		args = append(args, sql.Named(p.Name, v))
+	}
+	return args, nil
+}
+
+func (q Query) paramNames() string {
+	if len(q.Params) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(q.Params))
+	for _, p := range q.Params {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ConvertParam parses a textual parameter value into the Go value bound for type typ.
+func ConvertParam(typ, raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	switch strings.ToLower(typ) {
+	case "", "string":
+		return raw, nil
+	case "int":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q", raw)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", raw)
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", raw)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %q", typ)
+	}
+}
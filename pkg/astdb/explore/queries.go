@@ -6,9 +6,12 @@ import (
 )
 
 type Query struct {
-	ID          string `json:"id"`
-	Description string `json:"description"`
-	SQL         string `json:"sql"`
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	SQL         string   `json:"sql"`
+	Params      []Param  `json:"params,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Source      string   `json:"source,omitempty"`
 }
 
 func DefaultQueries() []Query {
@@ -18,13 +21,19 @@ func DefaultQueries() []Query {
 			Description: "Top AST node kinds by frequency",
 			SQL: `
 SELECT
-  kind,
+  n.kind,
   COUNT(*) AS n
-FROM nodes
-GROUP BY kind
+FROM nodes n
+JOIN files f ON f.file_id = n.file_id
+WHERE starts_with(f.path, $path_prefix)
+GROUP BY n.kind
 ORDER BY n DESC
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "PACKAGE_FILE_COUNTS",
@@ -34,10 +43,15 @@ SELECT
   coalesce(nullif(pkg_name, ''), '<unknown>') AS package_name,
   COUNT(*) AS file_count
 FROM files
+WHERE starts_with(path, $path_prefix)
 GROUP BY package_name
 ORDER BY file_count DESC, package_name
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "FILES_BY_NODE_COUNT",
@@ -48,280 +62,179 @@ SELECT
   COUNT(*) AS node_count
 FROM nodes n
 JOIN files f ON f.file_id = n.file_id
+WHERE starts_with(f.path, $path_prefix)
 GROUP BY f.path
 ORDER BY node_count DESC
-LIMIT 30
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(30),
+			},
 		},
 		{
 			ID:          "FUNCTIONS_PER_FILE",
 			Description: "Files with the highest function declaration count",
 			SQL: `
 SELECT
-  f.path,
+  path,
   COUNT(*) AS function_count
-FROM nodes n
-JOIN files f ON f.file_id = n.file_id
-WHERE n.kind = '*ast.FuncDecl'
-GROUP BY f.path
+FROM func_decls
+WHERE starts_with(path, $path_prefix)
+GROUP BY path
 ORDER BY function_count DESC
-LIMIT 30
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(30),
+			},
 		},
 		{
 			ID:          "LARGE_FUNCTIONS_BY_LINES",
 			Description: "Functions with largest line span (large-function heuristic)",
 			SQL: `
-WITH funcs AS (
-  SELECT file_id, ordinal AS func_ordinal, start_line, end_line
-  FROM nodes
-  WHERE kind = '*ast.FuncDecl'
-),
-func_names AS (
-  SELECT
-    file_id,
-    parent_ordinal AS func_ordinal,
-    node_text AS function_name,
-    ROW_NUMBER() OVER (PARTITION BY file_id, parent_ordinal ORDER BY ordinal) AS rn
-  FROM nodes
-  WHERE kind = '*ast.Ident' AND parent_ordinal IS NOT NULL
-)
 SELECT
-  f.path,
-  coalesce(fn.function_name, '<anonymous>') AS function_name,
-  funcs.start_line,
-  funcs.end_line,
-  (funcs.end_line - funcs.start_line + 1) AS line_span
-FROM funcs
-JOIN files f ON f.file_id = funcs.file_id
-LEFT JOIN func_names fn ON fn.file_id = funcs.file_id AND fn.func_ordinal = funcs.func_ordinal AND fn.rn = 1
-ORDER BY line_span DESC, f.path
-LIMIT 50
+  fd.path,
+  fd.name AS function_name,
+  fd.start_line,
+  fd.end_line,
+  fd.line_span,
+  m.sloc,
+  m.statements
+FROM func_decls fd
+JOIN function_metrics m ON m.file_id = fd.file_id AND m.ordinal = fd.ordinal
+WHERE starts_with(fd.path, $path_prefix)
+  AND fd.line_span >= $min_lines
+ORDER BY fd.line_span DESC, fd.path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_lines", Type: "int", Default: "0", Description: "minimum function line span"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "COMPLEX_FUNCTIONS_BY_BRANCHING",
-			Description: "Functions with high branching/control-flow counts",
+			Description: "Functions and function literals with high cyclomatic complexity",
 			SQL: `
-WITH funcs AS (
-  SELECT file_id, ordinal AS func_ordinal, start_line, end_line
-  FROM nodes
-  WHERE kind = '*ast.FuncDecl'
-),
-func_names AS (
-  SELECT
-    file_id,
-    parent_ordinal AS func_ordinal,
-    node_text AS function_name,
-    ROW_NUMBER() OVER (PARTITION BY file_id, parent_ordinal ORDER BY ordinal) AS rn
-  FROM nodes
-  WHERE kind = '*ast.Ident' AND parent_ordinal IS NOT NULL
-),
-signals AS (
-  SELECT
-    funcs.file_id,
-    funcs.func_ordinal,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.IfStmt') AS if_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.ForStmt') AS for_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.RangeStmt') AS range_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.SwitchStmt') AS switch_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.TypeSwitchStmt') AS type_switch_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.CaseClause') AS case_count,
-    COUNT(*) FILTER (WHERE n.kind = '*ast.SelectStmt') AS select_count
-  FROM funcs
-  JOIN nodes n
-    ON n.file_id = funcs.file_id
-   AND n.start_line >= funcs.start_line
-   AND n.end_line <= funcs.end_line
-  GROUP BY funcs.file_id, funcs.func_ordinal
-)
 SELECT
   f.path,
-  coalesce(fn.function_name, '<anonymous>') AS function_name,
-  signals.if_count,
-  signals.for_count,
-  signals.range_count,
-  signals.switch_count,
-  signals.type_switch_count,
-  signals.case_count,
-  signals.select_count,
-  (
-    signals.if_count + signals.for_count + signals.range_count +
-    signals.switch_count + signals.type_switch_count + signals.case_count + signals.select_count
-  ) AS branching_score
-FROM signals
-JOIN files f ON f.file_id = signals.file_id
-LEFT JOIN func_names fn ON fn.file_id = signals.file_id AND fn.func_ordinal = signals.func_ordinal AND fn.rn = 1
-ORDER BY branching_score DESC, f.path
-LIMIT 50
+  m.name AS function_name,
+  n.start_line,
+  m.cyclomatic,
+  m.cognitive,
+  m.max_nesting,
+  m.statements
+FROM function_metrics m
+JOIN files f ON f.file_id = m.file_id
+JOIN nodes n ON n.file_id = m.file_id AND n.ordinal = m.ordinal
+WHERE starts_with(f.path, $path_prefix)
+  AND m.cyclomatic >= $min_branching
+ORDER BY m.cyclomatic DESC, m.cognitive DESC, f.path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_branching", Type: "int", Default: "0", Description: "minimum cyclomatic complexity"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "SIGNATURES_WITH_MANY_FIELDS",
 			Description: "Functions with large signatures (params + returns field count)",
 			SQL: `
-WITH funcs AS (
-  SELECT file_id, ordinal AS func_ordinal
-  FROM nodes
-  WHERE kind = '*ast.FuncDecl'
-),
-func_names AS (
+WITH signature_fields AS (
   SELECT
-    file_id,
-    parent_ordinal AS func_ordinal,
-    node_text AS function_name,
-    ROW_NUMBER() OVER (PARTITION BY file_id, parent_ordinal ORDER BY ordinal) AS rn
-  FROM nodes
-  WHERE kind = '*ast.Ident' AND parent_ordinal IS NOT NULL
-),
-func_types AS (
-  SELECT file_id, parent_ordinal AS func_ordinal, ordinal AS func_type_ordinal
-  FROM nodes
-  WHERE kind = '*ast.FuncType' AND parent_ordinal IS NOT NULL
-),
-signature_fields AS (
-  SELECT
-    ft.file_id,
-    ft.func_ordinal,
-    COUNT(fd.ordinal) AS signature_field_count
-  FROM func_types ft
+    fd.file_id,
+    fd.ordinal,
+    COUNT(p.ordinal) AS signature_field_count
+  FROM func_decls fd
   JOIN nodes fl
-    ON fl.file_id = ft.file_id
-   AND fl.parent_ordinal = ft.func_type_ordinal
+    ON fl.file_id = fd.file_id
+   AND fl.parent_ordinal = fd.type_ordinal
    AND fl.kind = '*ast.FieldList'
-  LEFT JOIN nodes fd
-    ON fd.file_id = fl.file_id
-   AND fd.parent_ordinal = fl.ordinal
-   AND fd.kind = '*ast.Field'
-  GROUP BY ft.file_id, ft.func_ordinal
+  LEFT JOIN nodes p
+    ON p.file_id = fl.file_id
+   AND p.parent_ordinal = fl.ordinal
+   AND p.kind = '*ast.Field'
+  WHERE starts_with(fd.path, $path_prefix)
+  GROUP BY fd.file_id, fd.ordinal
 )
 SELECT
-  f.path,
-  coalesce(fn.function_name, '<anonymous>') AS function_name,
+  fd.path,
+  fd.name AS function_name,
   sf.signature_field_count
 FROM signature_fields sf
-JOIN funcs fu ON fu.file_id = sf.file_id AND fu.func_ordinal = sf.func_ordinal
-JOIN files f ON f.file_id = sf.file_id
-LEFT JOIN func_names fn ON fn.file_id = sf.file_id AND fn.func_ordinal = sf.func_ordinal AND fn.rn = 1
-ORDER BY sf.signature_field_count DESC, f.path
-LIMIT 50
+JOIN func_decls fd ON fd.file_id = sf.file_id AND fd.ordinal = sf.ordinal
+WHERE sf.signature_field_count >= $min_fields
+ORDER BY sf.signature_field_count DESC, fd.path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_fields", Type: "int", Default: "0", Description: "minimum params + results field count"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "LARGE_STRUCT_TYPES",
 			Description: "Struct types with many fields",
 			SQL: `
-WITH type_names AS (
-  SELECT
-    ts.file_id,
-    ts.ordinal AS type_spec_ordinal,
-    id.node_text AS type_name
-  FROM nodes ts
-  JOIN nodes id
-    ON id.file_id = ts.file_id
-   AND id.parent_ordinal = ts.ordinal
-   AND id.kind = '*ast.Ident'
-  WHERE ts.kind = '*ast.TypeSpec'
-),
-struct_types AS (
-  SELECT
-    st.file_id,
-    st.parent_ordinal AS type_spec_ordinal,
-    st.ordinal AS struct_ordinal
-  FROM nodes st
-  WHERE st.kind = '*ast.StructType' AND st.parent_ordinal IS NOT NULL
-),
-struct_fields AS (
-  SELECT
-    st.file_id,
-    st.type_spec_ordinal,
-    COUNT(fd.ordinal) AS field_count
-  FROM struct_types st
-  JOIN nodes fl
-    ON fl.file_id = st.file_id
-   AND fl.parent_ordinal = st.struct_ordinal
-   AND fl.kind = '*ast.FieldList'
-  LEFT JOIN nodes fd
-    ON fd.file_id = fl.file_id
-   AND fd.parent_ordinal = fl.ordinal
-   AND fd.kind = '*ast.Field'
-  GROUP BY st.file_id, st.type_spec_ordinal
-)
 SELECT
-  f.path,
-  coalesce(tn.type_name, '<anonymous_type>') AS type_name,
-  sf.field_count
-FROM struct_fields sf
-JOIN files f ON f.file_id = sf.file_id
-LEFT JOIN type_names tn ON tn.file_id = sf.file_id AND tn.type_spec_ordinal = sf.type_spec_ordinal
-ORDER BY sf.field_count DESC, f.path
-LIMIT 50
+  path,
+  coalesce(name, '<anonymous_type>') AS type_name,
+  field_count
+FROM struct_types
+WHERE starts_with(path, $path_prefix)
+  AND field_count >= $min_fields
+ORDER BY field_count DESC, path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_fields", Type: "int", Default: "0", Description: "minimum struct field count"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "LARGE_INTERFACES",
 			Description: "Interface types with many methods",
 			SQL: `
-WITH type_names AS (
-  SELECT
-    ts.file_id,
-    ts.ordinal AS type_spec_ordinal,
-    id.node_text AS type_name
-  FROM nodes ts
-  JOIN nodes id
-    ON id.file_id = ts.file_id
-   AND id.parent_ordinal = ts.ordinal
-   AND id.kind = '*ast.Ident'
-  WHERE ts.kind = '*ast.TypeSpec'
-),
-iface_types AS (
-  SELECT
-    it.file_id,
-    it.parent_ordinal AS type_spec_ordinal,
-    it.ordinal AS iface_ordinal
-  FROM nodes it
-  WHERE it.kind = '*ast.InterfaceType' AND it.parent_ordinal IS NOT NULL
-),
-iface_methods AS (
-  SELECT
-    it.file_id,
-    it.type_spec_ordinal,
-    COUNT(fd.ordinal) AS method_count
-  FROM iface_types it
-  JOIN nodes fl
-    ON fl.file_id = it.file_id
-   AND fl.parent_ordinal = it.iface_ordinal
-   AND fl.kind = '*ast.FieldList'
-  LEFT JOIN nodes fd
-    ON fd.file_id = fl.file_id
-   AND fd.parent_ordinal = fl.ordinal
-   AND fd.kind = '*ast.Field'
-  GROUP BY it.file_id, it.type_spec_ordinal
-)
 SELECT
-  f.path,
-  coalesce(tn.type_name, '<anonymous_type>') AS type_name,
-  im.method_count
-FROM iface_methods im
-JOIN files f ON f.file_id = im.file_id
-LEFT JOIN type_names tn ON tn.file_id = im.file_id AND tn.type_spec_ordinal = im.type_spec_ordinal
-ORDER BY im.method_count DESC, f.path
-LIMIT 50
+  path,
+  coalesce(name, '<anonymous_type>') AS type_name,
+  method_count
+FROM interfaces
+WHERE starts_with(path, $path_prefix)
+  AND method_count >= $min_methods
+ORDER BY method_count DESC, path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_methods", Type: "int", Default: "0", Description: "minimum interface method count"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "IMPORT_FREQUENCIES",
 			Description: "Most frequently imported packages",
 			SQL: `
 SELECT
-  replace(coalesce(n.node_text, ''), '"', '') AS import_path,
+  import_path,
   COUNT(*) AS uses
-FROM nodes n
-WHERE n.kind = '*ast.ImportSpec'
+FROM import_paths
+WHERE starts_with(path, $path_prefix)
 GROUP BY import_path
 ORDER BY uses DESC, import_path
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "THIRD_PARTY_IMPORTS",
@@ -330,16 +243,17 @@ LIMIT 50
 SELECT
   import_path,
   COUNT(*) AS uses
-FROM (
-  SELECT replace(coalesce(n.node_text, ''), '"', '') AS import_path
-  FROM nodes n
-  WHERE n.kind = '*ast.ImportSpec'
-) x
-WHERE import_path LIKE '%.%' OR import_path LIKE 'github.com/%' OR import_path LIKE 'golang.org/%'
+FROM import_paths
+WHERE NOT is_stdlib
+  AND starts_with(path, $path_prefix)
 GROUP BY import_path
 ORDER BY uses DESC, import_path
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "TOP_IDENTIFIERS",
@@ -349,12 +263,18 @@ SELECT
   n.node_text AS identifier,
   COUNT(*) AS uses
 FROM nodes n
+JOIN files f ON f.file_id = n.file_id
 WHERE n.kind = '*ast.Ident'
   AND coalesce(n.node_text, '') <> ''
+  AND starts_with(f.path, $path_prefix)
 GROUP BY n.node_text
 ORDER BY uses DESC, identifier
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "BLANK_IDENTIFIER_USAGE",
@@ -366,26 +286,36 @@ SELECT
 FROM nodes n
 JOIN files f ON f.file_id = n.file_id
 WHERE n.kind = '*ast.Ident' AND n.node_text = '_'
+  AND starts_with(f.path, $path_prefix)
 GROUP BY f.path
 ORDER BY blank_identifier_uses DESC, f.path
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "PANIC_USAGE",
 			Description: "Call sites that likely invoke panic",
 			SQL: `
 SELECT
-  f.path,
-  n.start_line AS line,
+  path,
+  start_line AS line,
   'panic' AS symbol,
-  'possible panic call' AS detail
-FROM nodes n
-JOIN files f ON f.file_id = n.file_id
-WHERE n.kind = '*ast.Ident' AND n.node_text = 'panic'
-ORDER BY f.path, line
-LIMIT 200
+  coalesce(func_name(file_id, ordinal), '<package scope>') AS function_name,
+  'panic call' AS detail
+FROM call_sites
+WHERE callee = 'panic'
+  AND starts_with(path, $path_prefix)
+ORDER BY path, line
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(200),
+			},
 		},
 		{
 			ID:          "GO_ROUTINE_SPAWNS",
@@ -397,78 +327,68 @@ SELECT
 FROM nodes n
 JOIN files f ON f.file_id = n.file_id
 WHERE n.kind = '*ast.GoStmt'
+  AND starts_with(f.path, $path_prefix)
 GROUP BY f.path
 ORDER BY go_stmt_count DESC, f.path
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "DEFER_HEAVY_FUNCTIONS",
 			Description: "Functions with many defer statements",
 			SQL: `
-WITH funcs AS (
-  SELECT file_id, ordinal AS func_ordinal, start_line, end_line
-  FROM nodes
-  WHERE kind = '*ast.FuncDecl'
-),
-func_names AS (
-  SELECT
-    file_id,
-    parent_ordinal AS func_ordinal,
-    node_text AS function_name,
-    ROW_NUMBER() OVER (PARTITION BY file_id, parent_ordinal ORDER BY ordinal) AS rn
-  FROM nodes
-  WHERE kind = '*ast.Ident' AND parent_ordinal IS NOT NULL
-),
-defer_counts AS (
+WITH defer_counts AS (
   SELECT
-    funcs.file_id,
-    funcs.func_ordinal,
+    fd.file_id,
+    fd.ordinal,
     COUNT(*) AS defer_count
-  FROM funcs
+  FROM func_decls fd
   JOIN nodes n
-    ON n.file_id = funcs.file_id
-   AND n.start_line >= funcs.start_line
-   AND n.end_line <= funcs.end_line
+    ON n.file_id = fd.file_id
+   AND n.start_offset >= fd.start_offset
+   AND n.end_offset <= fd.end_offset
    AND n.kind = '*ast.DeferStmt'
-  GROUP BY funcs.file_id, funcs.func_ordinal
+  WHERE starts_with(fd.path, $path_prefix)
+  GROUP BY fd.file_id, fd.ordinal
 )
 SELECT
-  f.path,
-  coalesce(fn.function_name, '<anonymous>') AS function_name,
+  fd.path,
+  fd.name AS function_name,
   dc.defer_count
 FROM defer_counts dc
-JOIN files f ON f.file_id = dc.file_id
-LEFT JOIN func_names fn ON fn.file_id = dc.file_id AND fn.func_ordinal = dc.func_ordinal AND fn.rn = 1
-ORDER BY dc.defer_count DESC, f.path
-LIMIT 50
+JOIN func_decls fd ON fd.file_id = dc.file_id AND fd.ordinal = dc.ordinal
+WHERE dc.defer_count >= $min_defers
+ORDER BY dc.defer_count DESC, fd.path
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_defers", Type: "int", Default: "1", Description: "minimum defer statements per function"},
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "INIT_FUNCTIONS",
 			Description: "Locations of init functions",
 			SQL: `
-WITH init_funcs AS (
-  SELECT
-    fd.file_id,
-    fd.start_line,
-    id.node_text AS function_name
-  FROM nodes fd
-  JOIN nodes id
-    ON id.file_id = fd.file_id
-   AND id.parent_ordinal = fd.ordinal
-   AND id.kind = '*ast.Ident'
-  WHERE fd.kind = '*ast.FuncDecl' AND id.node_text = 'init'
-)
 SELECT
-  f.path,
-  init_funcs.start_line AS line,
-  init_funcs.function_name
-FROM init_funcs
-JOIN files f ON f.file_id = init_funcs.file_id
-ORDER BY f.path, line
-LIMIT 200
+  path,
+  start_line AS line,
+  name AS function_name
+FROM functions
+WHERE name = 'init'
+  AND starts_with(path, $path_prefix)
+ORDER BY path, line
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(200),
+			},
 		},
 		{
 			ID:          "TEST_FILE_NODE_DENSITY",
@@ -480,10 +400,15 @@ SELECT
 FROM nodes n
 JOIN files f ON f.file_id = n.file_id
 WHERE f.path LIKE '%_test.go'
+  AND starts_with(f.path, $path_prefix)
 GROUP BY f.path
 ORDER BY node_count DESC, f.path
-LIMIT 50
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
 		},
 		{
 			ID:          "LITERAL_HEAVY_FILES",
@@ -495,10 +420,210 @@ SELECT
 FROM nodes n
 JOIN files f ON f.file_id = n.file_id
 WHERE n.kind = '*ast.BasicLit'
+  AND starts_with(f.path, $path_prefix)
 GROUP BY f.path
 ORDER BY literal_count DESC, f.path
-LIMIT 50
+LIMIT $limit
+`,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(50),
+			},
+		},
+		{
+			ID:          "DUPLICATE_CODE",
+			Description: "Clone groups: statements and functions whose structure repeats, ignoring names and literal values",
+			SQL: `
+WITH candidates AS (
+  SELECT n.file_id, n.ordinal, n.kind, n.subtree_hash, n.subtree_size, n.start_line, n.end_line
+  FROM nodes n
+  JOIN files f ON f.file_id = n.file_id
+  WHERE n.subtree_hash IS NOT NULL
+    AND n.subtree_size >= $min_nodes
+    AND n.end_line - n.start_line + 1 >= $min_lines
+    AND starts_with(f.path, $path_prefix)
+),
+dups AS (
+  SELECT *
+  FROM candidates
+  WHERE subtree_hash IN (SELECT subtree_hash FROM candidates GROUP BY subtree_hash HAVING COUNT(*) > 1)
+),
+-- A copy inside a larger duplicated subtree is already reported with it.
+covered AS (
+  SELECT DISTINCT d.file_id, d.ordinal
+  FROM dups d
+  JOIN dups a ON a.file_id = d.file_id AND a.ordinal < d.ordinal AND d.ordinal < a.ordinal + a.subtree_size
+),
+groups AS (
+  SELECT
+    d.subtree_hash,
+    COUNT(*) AS copies,
+    max(d.subtree_size) AS nodes,
+    max(d.end_line - d.start_line + 1) AS lines
+  FROM dups d
+  LEFT JOIN covered c ON c.file_id = d.file_id AND c.ordinal = d.ordinal
+  GROUP BY d.subtree_hash
+  HAVING bool_or(c.ordinal IS NULL)
+),
+ranked AS (
+  SELECT *, row_number() OVER (ORDER BY nodes DESC, copies DESC, subtree_hash) AS clone_group
+  FROM groups
+)
+SELECT
+  r.clone_group,
+  r.copies,
+  r.lines,
+  r.nodes,
+  d.kind,
+  f.path,
+  d.start_line,
+  d.end_line,
+  func_name(d.file_id, d.ordinal) AS function_name
+FROM ranked r
+JOIN dups d ON d.subtree_hash = r.subtree_hash
+JOIN files f ON f.file_id = d.file_id
+WHERE r.clone_group <= $limit
+ORDER BY r.clone_group, f.path, d.start_line
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "min_lines", Type: "int", Default: "5", Description: "minimum lines per copy"},
+				{Name: "min_nodes", Type: "int", Default: "30", Description: "minimum AST nodes per copy"},
+				{Name: "limit", Type: "int", Default: "20", Description: "maximum clone groups returned"},
+			},
+		},
+		{
+			ID:          "DEAD_CODE",
+			Description: "Package-level functions, methods, types, constants and variables nothing references (name-based, errs toward keeping code)",
+			SQL: `
+WITH RECURSIVE syms AS (
+  SELECT * FROM package_symbols
+),
+refs AS (
+  SELECT
+    r.file_id,
+    r.ordinal,
+    r.node_text AS name,
+    f.pkg_name,
+    CASE WHEN contains(f.path, '/') THEN regexp_replace(f.path, '/[^/]*$', '') ELSE '.' END AS pkg_dir
+  FROM nodes r
+  JOIN files f ON f.file_id = r.file_id
+  LEFT JOIN syms d ON d.file_id = r.file_id AND d.name_ordinal = r.ordinal
+  WHERE r.kind = '*ast.Ident' AND r.node_text <> '_' AND d.file_id IS NULL
+),
+-- One edge per reference, from the declaration enclosing it (NULL outside any
+-- symbol) to every symbol of that name in scope: the same package for
+-- unexported names, anywhere for exported ones. A method's receiver does not
+-- count as a use of its type.
+edges AS (
+  SELECT src.file_id AS src_file, src.name_ordinal AS src_name, t.file_id AS dst_file, t.name_ordinal AS dst_name
+  FROM refs r
+  JOIN syms t ON t.name = r.name AND (t.is_exported OR (t.pkg_dir = r.pkg_dir AND t.pkg_name = r.pkg_name))
+  LEFT JOIN syms src ON src.file_id = r.file_id AND r.ordinal > src.ordinal AND r.ordinal < src.ordinal + src.subtree_size
+  WHERE src.file_id IS NULL
+     OR NOT (
+       (src.file_id = t.file_id AND src.name_ordinal = t.name_ordinal)
+       OR (src.symbol_kind = 'method' AND t.symbol_kind = 'type' AND src.receiver = t.name AND src.pkg_dir = t.pkg_dir)
+     )
+),
+iface_methods AS (
+  SELECT DISTINCT m.node_text AS name
+  FROM nodes it
+  JOIN nodes fl ON fl.file_id = it.file_id AND fl.parent_ordinal = it.ordinal AND fl.field = 'Methods'
+  JOIN nodes fld ON fld.file_id = fl.file_id AND fld.parent_ordinal = fl.ordinal
+  JOIN nodes m ON m.file_id = fld.file_id AND m.parent_ordinal = fld.ordinal AND m.field = 'Names'
+  WHERE it.kind = '*ast.InterfaceType'
+),
+directives AS (
+  SELECT file_id, split_part(trim(text), ' ', 2) AS name
+  FROM comments
+  WHERE starts_with(text, '//go:linkname ') OR starts_with(text, '//export ')
+),
+allowed AS (
+  SELECT trim(p) AS prefix
+  FROM (SELECT unnest(string_split($allow, ',')) AS p)
+  WHERE trim(p) <> ''
+),
+-- Entry points are never reported; main_root and test_root mark where
+-- reachability starts.
+entry AS (
+  SELECT
+    s.file_id,
+    s.name_ordinal,
+    s.symbol_kind = 'func' AND NOT s.is_test_file AND (s.name = 'init' OR (s.name = 'main' AND s.pkg_name = 'main')) AS main_root,
+    s.symbol_kind = 'func' AND (s.name = 'init' OR (s.is_test_file AND regexp_matches(s.name, '^(Test|Benchmark|Fuzz|Example)'))) AS test_root
+  FROM syms s
+  WHERE (s.symbol_kind = 'func' AND (s.name = 'init' OR (s.name = 'main' AND s.pkg_name = 'main')))
+     OR (s.symbol_kind = 'func' AND s.is_test_file AND regexp_matches(s.name, '^(Test|Benchmark|Fuzz|Example)'))
+     OR (s.symbol_kind = 'method' AND (
+          s.name IN (SELECT name FROM iface_methods)
+          OR s.name IN (
+            'String', 'GoString', 'Format', 'Error', 'Unwrap', 'Is', 'As',
+            'MarshalJSON', 'UnmarshalJSON', 'MarshalText', 'UnmarshalText',
+            'MarshalBinary', 'UnmarshalBinary', 'MarshalYAML', 'UnmarshalYAML',
+            'GobEncode', 'GobDecode', 'Scan', 'Value', 'Set', 'ServeHTTP',
+            'Read', 'Write', 'Close', 'Seek', 'ReadAt', 'WriteAt', 'ReadFrom', 'WriteTo',
+            'Len', 'Less', 'Swap', 'Push', 'Pop', 'Deadline', 'Done', 'Err', 'Timeout', 'Temporary'
+          )))
+     OR EXISTS (SELECT 1 FROM directives d WHERE d.file_id = s.file_id AND d.name = s.name)
+     OR EXISTS (SELECT 1 FROM allowed a WHERE starts_with(s.path, a.prefix))
+),
+-- Package-level variables also seed the walk: their initializers run at
+-- program start even when the variable itself is unused.
+seeds AS (
+  SELECT file_id, name_ordinal
+  FROM entry
+  WHERE $reachable_from <> ''
+    AND NOT (main_root OR test_root)
+  UNION
+  SELECT file_id, name_ordinal
+  FROM entry
+  WHERE (main_root AND $reachable_from IN ('main', 'all'))
+     OR (test_root AND $reachable_from IN ('tests', 'all'))
+  UNION
+  SELECT file_id, name_ordinal
+  FROM syms
+  WHERE $reachable_from <> '' AND symbol_kind IN ('var', 'const')
+    AND NOT (is_test_file AND $reachable_from = 'main')
+),
+reach(file_id, name_ordinal) AS (
+  SELECT file_id, name_ordinal FROM seeds
+  UNION
+  SELECT e.dst_file, e.dst_name
+  FROM reach r
+  JOIN edges e ON e.src_file = r.file_id AND e.src_name = r.name_ordinal
+),
+live AS (
+  SELECT file_id, name_ordinal FROM entry
+  UNION
+  SELECT e.dst_file, e.dst_name
+  FROM edges e
+  LEFT JOIN reach r ON r.file_id = e.src_file AND r.name_ordinal = e.src_name
+  WHERE $reachable_from = '' OR e.src_file IS NULL OR r.file_id IS NOT NULL
+)
+SELECT
+  s.symbol_kind,
+  coalesce(s.receiver || '.', '') || s.name AS symbol,
+  s.pkg_dir AS package,
+  s.path,
+  s.start_line,
+  s.end_line
+FROM syms s
+LEFT JOIN live l ON l.file_id = s.file_id AND l.name_ordinal = s.name_ordinal
+WHERE l.file_id IS NULL
+  AND starts_with(s.path, $path_prefix)
+  AND ($exported OR NOT s.is_exported)
+  AND NOT (s.is_test_file AND $reachable_from = 'main')
+ORDER BY s.path, s.start_line, s.name
+LIMIT $limit
+`,
+			Params: []Param{
+				pathPrefixParam(),
+				{Name: "reachable_from", Type: "string", Default: "", Description: "empty to report unreferenced symbols; main, tests or all to report symbols unreachable from those entry points"},
+				{Name: "allow", Type: "string", Default: "", Description: "comma-separated path prefixes whose symbols are always kept, e.g. reflection-heavy packages"},
+				{Name: "exported", Type: "bool", Default: "true", Description: "also report exported symbols no indexed package uses"},
+				limitParam(500),
+			},
 		},
 		{
 			ID:          "PARSE_ERRORS",
@@ -509,22 +634,40 @@ SELECT
   parse_error
 FROM files
 WHERE parse_error IS NOT NULL AND parse_error <> ''
+  AND starts_with(path, $path_prefix)
 ORDER BY path
-LIMIT 100
+LIMIT $limit
 `,
+			Params: []Param{
+				pathPrefixParam(),
+				limitParam(100),
+			},
 		},
 	}
 }
 
-func SelectQueries(ids []string) ([]Query, error) {
+// SelectQueries returns the built-in helpers merged with extra (for example
+// from LoadHelpers), restricted to ids when any are given. A helper ID defined
+// twice is an error naming both sources.
+func SelectQueries(ids []string, extra ...Query) ([]Query, error) {
 	queries := DefaultQueries()
-	if len(ids) == 0 {
-		return queries, nil
+	for i := range queries {
+		queries[i].Source = SourceBuiltin
 	}
-	byID := make(map[string]Query, len(queries))
+	byID := make(map[string]Query, len(queries)+len(extra))
 	for _, q := range queries {
 		byID[q.ID] = q
 	}
+	for _, q := range extra {
+		if prev, ok := byID[q.ID]; ok {
+			return nil, fmt.Errorf("helper id %s is defined in both %s and %s", q.ID, prev.Source, q.Source)
+		}
+		byID[q.ID] = q
+		queries = append(queries, q)
+	}
+	if len(ids) == 0 {
+		return queries, nil
+	}
 	out := make([]Query, 0, len(ids))
 	for _, id := range ids {
 		id = strings.TrimSpace(id)
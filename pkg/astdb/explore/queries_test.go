@@ -1,6 +1,17 @@
 package explore
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
 
 func TestSelectQueries_Default(t *testing.T) {
 	t.Parallel()
@@ -47,3 +58,177 @@ func TestDefaultQueries_UniqueAndNonEmpty(t *testing.T) {
 		seen[q.ID] = struct{}{}
 	}
 }
+
+func TestQuery_Bind(t *testing.T) {
+	t.Parallel()
+
+	q := Query{ID: "Q", Params: []Param{
+		{Name: "min_lines", Type: "int", Default: "10"},
+		pathPrefixParam(),
+	}}
+	args, err := q.Bind(map[string]string{"path_prefix": "internal/"})
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := []sql.NamedArg{sql.Named("min_lines", int64(10)), sql.Named("path_prefix", "internal/")}
+	if len(args) != len(want) {
+		t.Fatalf("expected %d args, got %d", len(want), len(args))
+	}
+	for i, a := range args {
+		if a != want[i] {
+			t.Fatalf("arg %d: got %#v want %#v", i, a, want[i])
+		}
+	}
+
+	if _, err := q.Bind(map[string]string{"nope": "1"}); err == nil {
+		t.Fatal("expected error for unknown parameter")
+	}
+	if _, err := q.Bind(map[string]string{"min_lines": "many"}); err == nil {
+		t.Fatal("expected error for invalid int")
+	}
+}
+
+func TestDefaultQueries_RunWithParams(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	src := "package main\n\nfunc main() {\n\tif true {\n\t\tpanic(\"x\")\n\t}\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := governance.NewRunner(dbPath)
+	for _, q := range DefaultQueries() {
+		args, err := q.Bind(nil)
+		if err != nil {
+			t.Fatalf("%s: bind defaults: %v", q.ID, err)
+		}
+		if _, err := runner.QueryTable(context.Background(), q.SQL, args...); err != nil {
+			t.Fatalf("%s: %v", q.ID, err)
+		}
+	}
+
+	helpers, err := SelectQueries([]string{"LARGE_FUNCTIONS_BY_LINES"})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	for prefix, wantRows := range map[string]int{"": 1, "internal/": 0} {
+		args, err := helpers[0].Bind(map[string]string{"path_prefix": prefix, "min_lines": "5"})
+		if err != nil {
+			t.Fatalf("bind: %v", err)
+		}
+		table, err := runner.QueryTable(context.Background(), helpers[0].SQL, args...)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if len(table.Rows) != wantRows {
+			t.Fatalf("path_prefix=%q: expected %d rows, got %d", prefix, wantRows, len(table.Rows))
+		}
+	}
+}
+
+func TestDeadCode(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/m/lib"
+)
+
+type unused struct{ n int }
+
+func (u *unused) bump() { u.n++ }
+
+type shown struct{}
+
+func (shown) String() string { return "shown" }
+
+var counter = start()
+
+func start() int { return onlyTested() - 1 }
+
+func onlyTested() int { return 1 }
+
+func recurse(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return recurse(n - 1)
+}
+
+//go:linkname hidden runtime.hidden
+func hidden()
+
+func main() {
+	fmt.Println(shown{}, lib.Used())
+}
+`,
+		"main_test.go": "package main\n\nimport \"testing\"\n\nfunc TestOnly(t *testing.T) { _ = onlyTested() }\n",
+		"lib/lib.go":   "package lib\n\nconst Limit = 3\n\nfunc Used() int { return Limit }\n\nfunc Orphan() {}\n",
+		"refl/refl.go": "package refl\n\ntype Config struct{}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	helpers, err := SelectQueries([]string{"DEAD_CODE"})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	runner := governance.NewRunner(dbPath)
+	cases := []struct {
+		params map[string]string
+		want   string
+	}{
+		{map[string]string{"allow": "refl/"}, "Orphan,unused,unused.bump,counter,recurse"},
+		{map[string]string{"allow": "refl/", "exported": "false"}, "unused,unused.bump,counter,recurse"},
+		{map[string]string{}, "Orphan,unused,unused.bump,counter,recurse,Config"},
+		{map[string]string{"allow": "refl/", "reachable_from": "main"}, "Orphan,unused,unused.bump,counter,recurse"},
+		{map[string]string{"allow": "refl/", "reachable_from": "tests"}, "Limit,Used,Orphan,unused,unused.bump,shown,counter,recurse"},
+	}
+	for _, tc := range cases {
+		args, err := helpers[0].Bind(tc.params)
+		if err != nil {
+			t.Fatalf("bind: %v", err)
+		}
+		table, err := runner.QueryTable(context.Background(), helpers[0].SQL, args...)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		var got []string
+		for _, row := range table.Rows {
+			got = append(got, fmt.Sprint(row[1]))
+		}
+		if strings.Join(got, ",") != tc.want {
+			t.Fatalf("%v: got %v, want %s", tc.params, got, tc.want)
+		}
+	}
+}
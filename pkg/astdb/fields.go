@@ -0,0 +1,135 @@
+package astdb
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// NodeField is one AST-valued struct field of a node, such as CallExpr.Fun or
+// CallExpr.Args. Nodes holds the field's children in order; it is empty for a
+// nil field or an empty list.
+type NodeField struct {
+	Name  string
+	List  bool
+	Nodes []ast.Node
+}
+
+var (
+	nodeType         = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	commentGroupType = reflect.TypeOf((*ast.CommentGroup)(nil))
+)
+
+// NodeFields returns the fields ast.Inspect descends into, in declaration
+// order. Comment group fields (Doc, Comment) are included; File.Imports,
+// File.Comments and File.Unresolved repeat nodes found elsewhere and are not.
+func NodeFields(n ast.Node) []NodeField {
+	v := reflect.ValueOf(n)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	_, isFile := n.(*ast.File)
+	v = v.Elem()
+	t := v.Type()
+	out := make([]NodeField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if isFile && (sf.Name == "Imports" || sf.Name == "Comments" || sf.Name == "Unresolved") {
+			continue
+		}
+		fv := v.Field(i)
+		switch {
+		case isNodeType(sf.Type):
+			f := NodeField{Name: sf.Name}
+			if !fv.IsNil() {
+				f.Nodes = []ast.Node{fv.Interface().(ast.Node)}
+			}
+			out = append(out, f)
+		case sf.Type.Kind() == reflect.Slice && isNodeType(sf.Type.Elem()):
+			f := NodeField{Name: sf.Name, List: true}
+			for j := 0; j < fv.Len(); j++ {
+				if ev := fv.Index(j); !ev.IsNil() {
+					f.Nodes = append(f.Nodes, ev.Interface().(ast.Node))
+				}
+			}
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// IsCommentField reports whether f holds a Doc or Comment group.
+func IsCommentField(n ast.Node, f NodeField) bool {
+	sf, ok := reflect.TypeOf(n).Elem().FieldByName(f.Name)
+	return ok && sf.Type == commentGroupType
+}
+
+func isNodeType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Interface || t.Kind() == reflect.Pointer) && t.Implements(nodeType)
+}
+
+// fieldRef locates a node within its parent; Index is -1 outside lists.
+type fieldRef struct {
+	Name  string
+	Index int
+}
+
+func childFieldRefs(n ast.Node) map[ast.Node]fieldRef {
+	refs := make(map[ast.Node]fieldRef)
+	for _, f := range NodeFields(n) {
+		for i, c := range f.Nodes {
+			idx := -1
+			if f.List {
+				idx = i
+			}
+			refs[c] = fieldRef{Name: f.Name, Index: idx}
+		}
+	}
+	return refs
+}
+
+// NodeText is the text stored in nodes.node_text: the name of an identifier,
+// the value of a literal or import path, and the operator or keyword of
+// nodes whose meaning depends on one (x + y, x := y, i++, break, var, <-chan,
+// f(xs...)). Other nodes have no text.
+func NodeText(n ast.Node) string {
+	switch v := n.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.BasicLit:
+		return v.Value
+	case *ast.ImportSpec:
+		if v.Path != nil {
+			return v.Path.Value
+		}
+	case *ast.BinaryExpr:
+		return v.Op.String()
+	case *ast.UnaryExpr:
+		return v.Op.String()
+	case *ast.AssignStmt:
+		return v.Tok.String()
+	case *ast.IncDecStmt:
+		return v.Tok.String()
+	case *ast.BranchStmt:
+		return v.Tok.String()
+	case *ast.GenDecl:
+		return v.Tok.String()
+	case *ast.RangeStmt:
+		if v.Tok != token.ILLEGAL {
+			return v.Tok.String()
+		}
+	case *ast.ChanType:
+		switch v.Dir {
+		case ast.SEND:
+			return "chan<-"
+		case ast.RECV:
+			return "<-chan"
+		}
+		return "chan"
+	case *ast.CallExpr:
+		if v.Ellipsis.IsValid() {
+			return "..."
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package governance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const baselineVersion = 1
+
+// Baseline records accepted violations by fingerprint so checks can report
+// only new findings.
+type Baseline struct {
+	Version int             `json:"version"`
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is one accepted finding. Count covers identical findings,
+// such as two panics with the same message in one function.
+type BaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	RuleID      string `json:"rule_id"`
+	FilePath    string `json:"file_path"`
+	Symbol      string `json:"symbol,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+	Count       int    `json:"count"`
+}
+
+var (
+	digitRun = regexp.MustCompile(`[0-9]+`)
+	spaceRun = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint identifies a violation independently of its position: it hashes
+// the rule ID, file, symbol and the detail with digits and whitespace
+// normalized, so findings survive edits that only shift lines.
+func Fingerprint(v Violation) string {
+	detail := digitRun.ReplaceAllString(v.Detail, "#")
+	detail = strings.TrimSpace(spaceRun.ReplaceAllString(detail, " "))
+	sum := sha256.Sum256([]byte(strings.Join([]string{v.RuleID, v.FilePath, v.Symbol, detail}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewBaseline accepts every violation in violations.
+func NewBaseline(violations []Violation) Baseline {
+	byFP := make(map[string]*BaselineEntry)
+	for _, v := range violations {
+		fp := Fingerprint(v)
+		if e, ok := byFP[fp]; ok {
+			e.Count++
+			continue
+		}
+		byFP[fp] = &BaselineEntry{Fingerprint: fp, RuleID: v.RuleID, FilePath: v.FilePath, Symbol: v.Symbol, Detail: v.Detail, Count: 1}
+	}
+	b := Baseline{Version: baselineVersion, Entries: make([]BaselineEntry, 0, len(byFP))}
+	for _, e := range byFP {
+		b.Entries = append(b.Entries, *e)
+	}
+	sort.Slice(b.Entries, func(i, j int) bool {
+		a, c := b.Entries[i], b.Entries[j]
+		if a.RuleID != c.RuleID {
+			return a.RuleID < c.RuleID
+		}
+		if a.FilePath != c.FilePath {
+			return a.FilePath < c.FilePath
+		}
+		return a.Fingerprint < c.Fingerprint
+	})
+	return b
+}
+
+// ReadBaseline loads a baseline written by Write.
+func ReadBaseline(path string) (Baseline, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Baseline{}, fmt.Errorf("read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return Baseline{}, fmt.Errorf("parse baseline %s: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return Baseline{}, fmt.Errorf("baseline %s has version %d, want %d; rewrite it with --write-baseline", path, b.Version, baselineVersion)
+	}
+	return b, nil
+}
+
+// Write stores the baseline as indented JSON, creating parent directories.
+func (b Baseline) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create baseline dir: %w", err)
+	}
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	return nil
+}
+
+// Compare splits violations into those not covered by the baseline and
+// returns the baseline entries no violation matched any more. An entry with
+// Count n covers up to n identical violations; fixed entries carry the
+// unmatched remainder as their Count.
+func (b Baseline) Compare(violations []Violation) (fresh []Violation, fixed []BaselineEntry) {
+	remaining := make(map[string]int, len(b.Entries))
+	for _, e := range b.Entries {
+		remaining[e.Fingerprint] += e.Count
+	}
+	fresh = make([]Violation, 0)
+	for _, v := range violations {
+		fp := Fingerprint(v)
+		if remaining[fp] > 0 {
+			remaining[fp]--
+			continue
+		}
+		fresh = append(fresh, v)
+	}
+	fixed = make([]BaselineEntry, 0)
+	for _, e := range b.Entries {
+		if n := remaining[e.Fingerprint]; n > 0 {
+			e.Count = n
+			remaining[e.Fingerprint] = 0
+			fixed = append(fixed, e)
+		}
+	}
+	return fresh, fixed
+}
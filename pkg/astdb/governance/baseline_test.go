@@ -0,0 +1,51 @@
+package governance
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint_IgnoresPositionAndNumbers(t *testing.T) {
+	t.Parallel()
+
+	base := Violation{RuleID: "NO_PANIC", FilePath: "a.go", Symbol: "Load", Detail: "panic at line 12", Line: 12}
+	moved := base
+	moved.Line = 40
+	moved.Column = 3
+	moved.Detail = "panic  at line 39"
+	if Fingerprint(base) != Fingerprint(moved) {
+		t.Fatalf("fingerprint changed when only the position moved")
+	}
+	other := base
+	other.Symbol = "Store"
+	if Fingerprint(base) == Fingerprint(other) {
+		t.Fatalf("fingerprint ignored the symbol")
+	}
+}
+
+func TestBaseline_CompareCountsAndRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dup := Violation{RuleID: "NO_PANIC", FilePath: "a.go", Symbol: "Load", Detail: "panic"}
+	gone := Violation{RuleID: "NO_PANIC", FilePath: "b.go", Symbol: "Save", Detail: "panic"}
+	path := filepath.Join(t.TempDir(), "nested", "baseline.json")
+	if err := NewBaseline([]Violation{dup, dup, gone}).Write(path); err != nil {
+		t.Fatalf("write baseline: %v", err)
+	}
+	baseline, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatalf("read baseline: %v", err)
+	}
+	if len(baseline.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", baseline.Entries)
+	}
+
+	added := Violation{RuleID: "NO_PANIC", FilePath: "c.go", Symbol: "Run", Detail: "panic"}
+	fresh, fixed := baseline.Compare([]Violation{dup, dup, dup, added})
+	if len(fresh) != 2 || fresh[0].FilePath != "a.go" || fresh[1].FilePath != "c.go" {
+		t.Fatalf("expected the third duplicate and c.go to be new, got %+v", fresh)
+	}
+	if len(fixed) != 1 || fixed[0].FilePath != "b.go" || fixed[0].Count != 1 {
+		t.Fatalf("expected b.go to be fixed, got %+v", fixed)
+	}
+}
@@ -0,0 +1,210 @@
+package governance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Result columns Run maps onto Violation fields. A rule locates its findings
+// with file_path or with the file_id and ordinal of a node, and may propose a
+// fix with the fix_ columns.
+var (
+	textColumns    = []string{"file_path", "symbol", "detail", "fix_text"}
+	integerColumns = []string{"line", "column", "end_line", "end_column", "file_id", "ordinal", "fix_start_offset", "fix_end_offset"}
+)
+
+var integerTypes = map[string]struct{}{
+	"TINYINT": {}, "SMALLINT": {}, "INTEGER": {}, "BIGINT": {}, "HUGEINT": {},
+	"UTINYINT": {}, "USMALLINT": {}, "UINTEGER": {}, "UBIGINT": {},
+}
+
+// ValidateRuleSQL checks rule.QuerySQL, or the query compiled from
+// rule.Pattern, against the database schema without running it. The query must be a single SELECT whose result locates each
+// finding (file_path, or file_id and ordinal), with integer position columns
+// and scalar text columns. Problems that leave findings incomplete, such as a
+// missing detail column or a likely misspelled one, are returned as warnings.
+func (r *Runner) ValidateRuleSQL(ctx context.Context, rule Rule) ([]string, error) {
+	db, release, err := r.open()
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+	query, err := ruleQuery(rule)
+	if err != nil {
+		return nil, err
+	}
+	return validateContract(ctx, db, query)
+}
+
+func validateContract(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	if err := checkSingleSelect(ctx, db, query); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `DESCRIBE `+query)
+	if err != nil {
+		return nil, fmt.Errorf("query does not bind against the schema: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string)
+	names := make([]string, 0)
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		name := asString(vals[0])
+		types[name] = strings.ToUpper(asString(vals[1]))
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, hasPath := types["file_path"]
+	_, hasFileID := types["file_id"]
+	_, hasOrdinal := types["ordinal"]
+	if !hasPath && !(hasFileID && hasOrdinal) {
+		return nil, fmt.Errorf("result must include file_path, or file_id and ordinal (got %s)", strings.Join(names, ", "))
+	}
+	_, hasFixStart := types["fix_start_offset"]
+	_, hasFixEnd := types["fix_end_offset"]
+	_, hasFixText := types["fix_text"]
+	if (hasFixStart || hasFixEnd || hasFixText) && !(hasFixStart && hasFixEnd) {
+		return nil, errors.New("a fix needs both fix_start_offset and fix_end_offset")
+	}
+	for _, col := range integerColumns {
+		if typ, ok := types[col]; ok {
+			if _, isInt := integerTypes[typ]; !isInt {
+				return nil, fmt.Errorf("column %s must be an integer, got %s", col, typ)
+			}
+		}
+	}
+	for _, col := range textColumns {
+		if typ, ok := types[col]; ok && !isScalarType(typ) {
+			return nil, fmt.Errorf("column %s must be a scalar, got %s", col, typ)
+		}
+	}
+
+	warnings := make([]string, 0)
+	if _, ok := types["detail"]; !ok {
+		warnings = append(warnings, "no detail column; findings will have an empty message")
+	}
+	if _, ok := types["line"]; !ok && !(hasFileID && hasOrdinal) {
+		warnings = append(warnings, "no line column; findings will have no position")
+	}
+	known := append(append([]string{}, textColumns...), integerColumns...)
+	sort.Strings(names)
+	for _, name := range names {
+		if contains(known, name) {
+			continue
+		}
+		if near := nearColumn(name, known); near != "" {
+			if _, present := types[near]; !present {
+				warnings = append(warnings, fmt.Sprintf("column %s looks like a misspelling of %s; it will only appear in raw values", name, near))
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// checkSingleSelect parses query without running it. DESCRIBE would execute
+// every statement after the first, so anything but one SELECT is refused here.
+func checkSingleSelect(ctx context.Context, db *sql.DB, query string) error {
+	var raw string
+	if err := db.QueryRowContext(ctx, `SELECT json_serialize_sql(?::VARCHAR)::VARCHAR`, query).Scan(&raw); err != nil {
+		return fmt.Errorf("parse query: %w", err)
+	}
+	var parsed struct {
+		Error        bool              `json:"error"`
+		ErrorType    string            `json:"error_type"`
+		ErrorMessage string            `json:"error_message"`
+		Statements   []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return fmt.Errorf("parse query: %w", err)
+	}
+	switch {
+	case parsed.Error && parsed.ErrorType == "parser":
+		return fmt.Errorf("syntax error: %s", parsed.ErrorMessage)
+	case parsed.Error, len(parsed.Statements) != 1:
+		return errors.New("query must be a single read-only SELECT statement")
+	}
+	return nil
+}
+
+func isScalarType(typ string) bool {
+	return !strings.HasSuffix(typ, "]") && !strings.HasPrefix(typ, "STRUCT") && !strings.HasPrefix(typ, "MAP") && !strings.HasPrefix(typ, "UNION")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nearColumn returns the recognized column name is likely meant to be: the
+// same letters in another case or separator style, or a small edit away.
+func nearColumn(name string, known []string) string {
+	squash := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '_' || r == '-' || r == ' ' {
+				return -1
+			}
+			return r
+		}, strings.ToLower(s))
+	}
+	for _, k := range known {
+		if squash(name) == squash(k) {
+			return k
+		}
+	}
+	if len(name) < 4 {
+		return ""
+	}
+	for _, k := range known {
+		limit := 1
+		if len(k) > 5 {
+			limit = 2
+		}
+		if editDistance(strings.ToLower(name), k) <= limit {
+			return k
+		}
+	}
+	return ""
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
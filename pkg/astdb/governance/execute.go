@@ -0,0 +1,142 @@
+package governance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultRuleTimeout bounds a rule's query when RunOptions.RuleTimeout is 0.
+const DefaultRuleTimeout = time.Minute
+
+// RuleStat is the outcome of one rule in a run. Rows counts the rows the rule
+// returned, before suppressions. A failed rule has Error set and contributes
+// no violations.
+type RuleStat struct {
+	RuleID   string        `json:"rule_id"`
+	Elapsed  time.Duration `json:"elapsed_ns"`
+	Rows     int           `json:"rows"`
+	Error    string        `json:"error,omitempty"`
+	TimedOut bool          `json:"timed_out,omitempty"`
+}
+
+// Failed reports whether the rule errored or timed out.
+func (s RuleStat) Failed() bool { return s.Error != "" }
+
+// runRules executes the enabled rules concurrently, each under its own
+// timeout. Violations keep the order of rules; stats cover every rule run.
+func runRules(ctx context.Context, db *sql.DB, rules []Rule, opts RunOptions) ([]Violation, []RuleStat) {
+	enabled := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	workers := opts.Parallelism
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	timeout := opts.RuleTimeout
+	if timeout == 0 {
+		timeout = DefaultRuleTimeout
+	}
+
+	found := make([][]Violation, len(enabled))
+	stats := make([]RuleStat, len(enabled))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, rule := range enabled {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found[i], stats[i] = runRule(ctx, db, rule, timeout)
+		}()
+	}
+	wg.Wait()
+
+	out := make([]Violation, 0)
+	for _, vs := range found {
+		out = append(out, vs...)
+	}
+	return out, stats
+}
+
+func runRule(ctx context.Context, db *sql.DB, rule Rule, timeout time.Duration) ([]Violation, RuleStat) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	out, err := queryRule(ctx, db, rule)
+	stat := RuleStat{RuleID: rule.ID, Elapsed: time.Since(start), Rows: len(out)}
+	if err != nil {
+		stat.Rows = 0
+		stat.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+		if stat.TimedOut {
+			stat.Error = fmt.Sprintf("timed out after %s", timeout)
+		} else {
+			stat.Error = err.Error()
+		}
+		return nil, stat
+	}
+	return out, stat
+}
+
+func queryRule(ctx context.Context, db *sql.DB, rule Rule) ([]Violation, error) {
+	rows, err := db.QueryContext(ctx, rule.QuerySQL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Violation, 0)
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		raw := make(map[string]any, len(cols))
+		for i, col := range cols {
+			raw[col] = normalize(vals[i])
+		}
+		out = append(out, Violation{
+			RuleID:    rule.ID,
+			Category:  rule.Category,
+			Severity:  rule.Severity,
+			FilePath:  asString(raw["file_path"]),
+			Symbol:    asString(raw["symbol"]),
+			Detail:    asString(raw["detail"]),
+			Line:      asInt(raw["line"]),
+			Column:    asInt(raw["column"]),
+			EndLine:   asInt(raw["end_line"]),
+			EndColumn: asInt(raw["end_column"]),
+			Fix:       readFix(raw),
+			RawValues: raw,
+		})
+	}
+	return out, rows.Err()
+}
+
+// readFix returns the edit in a rule's fix columns, or nil when the row has
+// none. A NULL fix_text deletes the range.
+func readFix(raw map[string]any) *Fix {
+	start, end := raw["fix_start_offset"], raw["fix_end_offset"]
+	if start == nil || end == nil {
+		return nil
+	}
+	return &Fix{StartOffset: asInt(start), EndOffset: asInt(end), Text: asString(raw["fix_text"])}
+}
@@ -0,0 +1,135 @@
+package governance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/edit"
+)
+
+// FileFix is the outcome of applying the fixes proposed for one file. Nothing
+// is written; callers write After when Err is nil.
+type FileFix struct {
+	FilePath string      `json:"file_path"`
+	Before   []byte      `json:"-"`
+	After    []byte      `json:"-"`
+	Applied  []Violation `json:"applied"`
+	Skipped  []Skipped   `json:"skipped,omitempty"`
+	// Err is set when the file cannot be fixed at all: it changed since it
+	// was indexed, or the fixed source does not gofmt.
+	Err error `json:"-"`
+}
+
+// Skipped is a fix that was left out of a FileFix.
+type Skipped struct {
+	Violation Violation `json:"violation"`
+	Reason    string    `json:"reason"`
+}
+
+// PlanFixes applies the fixes of violations in memory, one FileFix per file in
+// path order. A file whose content no longer matches the index is refused. A
+// fix that overlaps one already taken is skipped; a second check run after
+// writing the result picks it up. Fixed files are gofmt'ed.
+func (r *Runner) PlanFixes(ctx context.Context, repoRoot string, violations []Violation) ([]FileFix, error) {
+	byFile := make(map[string][]Violation)
+	for _, v := range violations {
+		if v.Fix != nil && v.FilePath != "" {
+			byFile[v.FilePath] = append(byFile[v.FilePath], v)
+		}
+	}
+	if len(byFile) == 0 {
+		return nil, nil
+	}
+	hashes, err := r.contentHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	out := make([]FileFix, 0, len(paths))
+	for _, path := range paths {
+		out = append(out, planFile(filepath.Join(repoRoot, filepath.FromSlash(path)), path, hashes[path], byFile[path]))
+	}
+	return out, nil
+}
+
+func planFile(abs, path, hash string, violations []Violation) FileFix {
+	ff := FileFix{FilePath: path}
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		ff.Err = err
+		return ff
+	}
+	ff.Before = src
+	sum := sha256.Sum256(src)
+	if hash == "" || hex.EncodeToString(sum[:]) != hash {
+		ff.Err = errors.New("file changed since it was indexed; run check again")
+		return ff
+	}
+
+	// Earlier fixes win, so the result does not depend on rule timing.
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Fix.StartOffset < violations[j].Fix.StartOffset
+	})
+	edits := make([]edit.Edit, 0, len(violations))
+	for _, v := range violations {
+		e := edit.Edit{Start: v.Fix.StartOffset, End: v.Fix.EndOffset, Text: v.Fix.Text}
+		if err := edit.Validate(len(src), append(edits, e)); err != nil {
+			var overlap *edit.OverlapError
+			reason := err.Error()
+			if errors.As(err, &overlap) {
+				reason = "overlaps another fix"
+			}
+			ff.Skipped = append(ff.Skipped, Skipped{Violation: v, Reason: reason})
+			continue
+		}
+		edits = append(edits, e)
+		ff.Applied = append(ff.Applied, v)
+	}
+	after, err := edit.Apply(src, edits)
+	if err != nil {
+		ff.Err = err
+		return ff
+	}
+	formatted, err := format.Source(after)
+	if err != nil {
+		ff.Err = fmt.Errorf("fixed source does not format: %w", err)
+		return ff
+	}
+	ff.After = formatted
+	return ff
+}
+
+// contentHashes maps indexed file paths to the hash of their content.
+func (r *Runner) contentHashes(ctx context.Context) (map[string]string, error) {
+	db, release, err := r.open()
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+	rows, err := db.QueryContext(ctx, `SELECT path, COALESCE(content_hash, '') FROM files`)
+	if err != nil {
+		return nil, fmt.Errorf("read file hashes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, err
+		}
+		out[path] = hash
+	}
+	return out, rows.Err()
+}
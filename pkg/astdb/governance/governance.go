@@ -5,56 +5,102 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+	"github.com/Yacobolo/goastdb/pkg/astdb/pattern"
 	_ "github.com/duckdb/duckdb-go/v2"
 )
 
 type Rule struct {
-	ID          string
-	Category    string
-	Severity    string
-	Description string
-	QuerySQL    string
-	Enabled     bool
+	ID          string `json:"id"`
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	QuerySQL    string `json:"query_sql"`
+	// Pattern is a Go pattern (see package pattern) used instead of
+	// QuerySQL; every match is a violation.
+	Pattern string `json:"pattern,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Source is SourceBuiltin, a rule file path, or empty for rules added
+	// through UpsertRules.
+	Source string `json:"source,omitempty"`
+	// location is the file:line a file rule was read from, for errors.
+	location string
 }
 
 type Violation struct {
-	RuleID    string
-	Category  string
-	Severity  string
-	FilePath  string
-	Symbol    string
-	Detail    string
-	Line      int
-	RawValues map[string]any
+	RuleID    string         `json:"rule_id"`
+	Category  string         `json:"category"`
+	Severity  string         `json:"severity"`
+	FilePath  string         `json:"file_path"`
+	Symbol    string         `json:"symbol"`
+	Detail    string         `json:"detail"`
+	Line      int            `json:"line"`
+	Column    int            `json:"column,omitempty"`
+	EndLine   int            `json:"end_line,omitempty"`
+	EndColumn int            `json:"end_column,omitempty"`
+	Fix       *Fix           `json:"fix,omitempty"`
+	RawValues map[string]any `json:"raw_values,omitempty"`
+}
+
+// Fix is a text edit that resolves a violation: bytes [StartOffset, EndOffset)
+// of the violation's file are replaced with Text.
+type Fix struct {
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Text        string `json:"text"`
 }
 
 type Row map[string]any
 
 type Table struct {
 	Columns []string `json:"columns"`
+	Types   []string `json:"types,omitempty"`
 	Rows    [][]any  `json:"rows,omitempty"`
 }
 
 type RunOptions struct {
 	RuleIDs []string
+	// Parallelism bounds how many rules run at once; 0 means GOMAXPROCS.
+	Parallelism int
+	// RuleTimeout bounds each rule's query; 0 means DefaultRuleTimeout and a
+	// negative value disables the limit.
+	RuleTimeout time.Duration
 }
 
 type Runner struct {
 	duckDBPath string
+	db         *sql.DB
 }
 
 func NewRunner(duckDBPath string) *Runner { return &Runner{duckDBPath: duckDBPath} }
 
+// NewRunnerFromDB runs against an already open database, such as a read-only
+// snapshot. The caller keeps ownership of db.
+func NewRunnerFromDB(db *sql.DB) *Runner { return &Runner{db: db} }
+
+func (r *Runner) open() (*sql.DB, func(), error) {
+	if r.db != nil {
+		return r.db, func() {}, nil
+	}
+	db, err := sql.Open("duckdb", r.duckDBPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, func() { _ = db.Close() }, nil
+}
+
 func ValidateRule(rule Rule) error {
 	rule.ID = strings.TrimSpace(rule.ID)
 	rule.Category = strings.TrimSpace(rule.Category)
 	rule.Severity = strings.ToLower(strings.TrimSpace(rule.Severity))
 	rule.Description = strings.TrimSpace(rule.Description)
 	rule.QuerySQL = strings.TrimSpace(rule.QuerySQL)
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
 
 	if rule.ID == "" {
 		return errors.New("rule id is required")
@@ -65,88 +111,238 @@ func ValidateRule(rule Rule) error {
 	if rule.Description == "" {
 		return fmt.Errorf("rule %s: description is required", rule.ID)
 	}
-	if rule.QuerySQL == "" {
-		return fmt.Errorf("rule %s: query_sql is required", rule.ID)
+	switch {
+	case rule.QuerySQL == "" && rule.Pattern == "":
+		return fmt.Errorf("rule %s: query_sql or pattern is required", rule.ID)
+	case rule.QuerySQL != "" && rule.Pattern != "":
+		return fmt.Errorf("rule %s: set query_sql or pattern, not both", rule.ID)
+	case rule.Pattern != "":
+		if _, err := pattern.Parse(rule.Pattern); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
 	}
-	switch rule.Severity {
-	case "critical", "error", "warning", "info":
-		return nil
-	default:
+	if SeverityRank(rule.Severity) == 0 {
 		return fmt.Errorf("rule %s: invalid severity %q", rule.ID, rule.Severity)
 	}
+	return nil
+}
+
+// ruleQuery returns the SQL a rule runs: its QuerySQL, or the query compiled
+// from its Pattern, which reports each match with the rule's description.
+func ruleQuery(rule Rule) (string, error) {
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return rule.QuerySQL, nil
+	}
+	p, err := pattern.Parse(rule.Pattern)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`SELECT file_path, line, "column", end_line, end_column, func_name(file_id, ordinal) AS symbol, '%s' AS detail
+FROM (
+%s
+) m`, strings.ReplaceAll(rule.Description, "'", "''"), p.SQL()), nil
 }
 
 func (r *Runner) UpsertRules(ctx context.Context, rules []Rule) error {
-	if len(rules) == 0 {
+	return r.writeRules(ctx, rules, true)
+}
+
+// writeRules deletes the rules in prune and inserts rules in one transaction,
+// after validating every rule so a bad one leaves the table as it was.
+// With replace set, existing rows are overwritten. Without it, only built-in
+// rows whose definition changed are refreshed, keeping the user's
+// enable/disable choice and any rule that overrides a built-in ID.
+func (r *Runner) writeRules(ctx context.Context, rules []Rule, replace bool, prune ...string) error {
+	if len(rules) == 0 && len(prune) == 0 {
 		return nil
 	}
-	db, err := sql.Open("duckdb", r.duckDBPath)
+	db, release, err := r.open()
 	if err != nil {
 		return fmt.Errorf("open duckdb: %w", err)
 	}
-	defer func() { _ = db.Close() }()
-
-	if _, err := db.ExecContext(ctx, `
-CREATE TABLE IF NOT EXISTS governance_rules (
-	rule_id TEXT PRIMARY KEY,
-	category TEXT NOT NULL,
-	severity TEXT NOT NULL,
-	description TEXT NOT NULL,
-	query_sql TEXT NOT NULL,
-	enabled BOOLEAN NOT NULL DEFAULT true,
-	updated_unix BIGINT NOT NULL
-)`); err != nil {
-		return fmt.Errorf("ensure governance_rules table: %w", err)
-	}
-
-	stmt, err := db.PrepareContext(ctx, `
-INSERT INTO governance_rules (rule_id, category, severity, description, query_sql, enabled, updated_unix)
-VALUES (?, ?, ?, ?, ?, ?, ?)
-ON CONFLICT(rule_id) DO UPDATE SET
+	defer release()
+
+	if err := astdb.EnsureRulesTable(ctx, db); err != nil {
+		return err
+	}
+
+	rules = append([]Rule(nil), rules...)
+	queries := make([]string, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		rule.ID = strings.TrimSpace(rule.ID)
+		rule.Category = strings.TrimSpace(rule.Category)
+		rule.Severity = strings.ToLower(strings.TrimSpace(rule.Severity))
+		rule.Description = strings.TrimSpace(rule.Description)
+		rule.QuerySQL = strings.TrimSpace(rule.QuerySQL)
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		if err := ValidateRule(*rule); err != nil {
+			return locateRuleError(*rule, err)
+		}
+		query, err := ruleQuery(*rule)
+		if err != nil {
+			return locateRuleError(*rule, fmt.Errorf("rule %s: %w", rule.ID, err))
+		}
+		// Built-in rules are checked by TestDefaultRules_Contract instead of on
+		// every command.
+		if rule.Source != SourceBuiltin {
+			if _, err := validateContract(ctx, db, query); err != nil {
+				return locateRuleError(*rule, fmt.Errorf("rule %s: %w", rule.ID, err))
+			}
+		}
+		queries[i] = query
+	}
+
+	conflict := `DO UPDATE SET
+	category=excluded.category,
+	severity=excluded.severity,
+	description=excluded.description,
+	query_sql=excluded.query_sql,
+	updated_unix=excluded.updated_unix,
+	pattern=excluded.pattern
+WHERE governance_rules.source = excluded.source AND (
+	governance_rules.category IS DISTINCT FROM excluded.category OR
+	governance_rules.severity IS DISTINCT FROM excluded.severity OR
+	governance_rules.description IS DISTINCT FROM excluded.description OR
+	governance_rules.query_sql IS DISTINCT FROM excluded.query_sql OR
+	governance_rules.pattern IS DISTINCT FROM excluded.pattern)`
+	if replace {
+		conflict = `DO UPDATE SET
 	category=excluded.category,
 	severity=excluded.severity,
 	description=excluded.description,
 	query_sql=excluded.query_sql,
 	enabled=excluded.enabled,
-	updated_unix=excluded.updated_unix`)
+	updated_unix=excluded.updated_unix,
+	source=excluded.source,
+	pattern=excluded.pattern`
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, id := range prune {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM governance_rules WHERE rule_id = ?`, id); err != nil {
+			return fmt.Errorf("prune rule %s: %w", id, err)
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO governance_rules (rule_id, category, severity, description, query_sql, enabled, updated_unix, source, pattern)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(rule_id) `+conflict)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = stmt.Close() }()
 
 	now := time.Now().Unix()
-	for _, rule := range rules {
-		rule.ID = strings.TrimSpace(rule.ID)
-		rule.Category = strings.TrimSpace(rule.Category)
-		rule.Severity = strings.ToLower(strings.TrimSpace(rule.Severity))
-		rule.Description = strings.TrimSpace(rule.Description)
-		rule.QuerySQL = strings.TrimSpace(rule.QuerySQL)
-		if err := ValidateRule(rule); err != nil {
+	for i, rule := range rules {
+		if _, err := stmt.ExecContext(ctx, rule.ID, rule.Category, rule.Severity, rule.Description, queries[i], rule.Enabled, now, rule.Source, rule.Pattern); err != nil {
+			return locateRuleError(rule, fmt.Errorf("upsert rule %s: %w", rule.ID, err))
+		}
+	}
+	return tx.Commit()
+}
+
+// locateRuleError prefixes err with the file and line a file rule came from.
+func locateRuleError(rule Rule, err error) error {
+	if rule.location == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", rule.location, err)
+}
+
+// EnsureDefaultRules adds built-in rules that are missing from the table and
+// brings stored built-ins up to date with this binary, keeping their enabled
+// flag.
+func (r *Runner) EnsureDefaultRules(ctx context.Context) error {
+	rules := defaultRules()
+	for i := range rules {
+		rules[i].Source = SourceBuiltin
+	}
+	return r.writeRules(ctx, rules, false)
+}
+
+// SetRuleEnabled enables or disables the rules with the given IDs.
+func (r *Runner) SetRuleEnabled(ctx context.Context, enabled bool, ids ...string) error {
+	if err := r.EnsureDefaultRules(ctx); err != nil {
+		return err
+	}
+	db, release, err := r.open()
+	if err != nil {
+		return fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+
+	now := time.Now().Unix()
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		source, err := ruleSource(ctx, db, id)
+		if err != nil {
 			return err
 		}
-		if _, err := stmt.ExecContext(ctx, rule.ID, rule.Category, rule.Severity, rule.Description, rule.QuerySQL, rule.Enabled, now); err != nil {
-			return fmt.Errorf("upsert rule %s: %w", rule.ID, err)
+		if isFileSource(source) {
+			return fmt.Errorf("rule %s is defined in %s; set enabled there", id, source)
+		}
+		if _, err := db.ExecContext(ctx, `UPDATE governance_rules SET enabled = ?, updated_unix = ? WHERE rule_id = ?`, enabled, now, id); err != nil {
+			return fmt.Errorf("update rule %s: %w", id, err)
 		}
 	}
+	return nil
+}
+
+// DeleteRule removes user-defined rules. Built-in rules are recreated on every
+// run, so they can only be disabled.
+func (r *Runner) DeleteRule(ctx context.Context, ids ...string) error {
+	db, release, err := r.open()
+	if err != nil {
+		return fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
 
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		source, err := ruleSource(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		switch {
+		case source == SourceBuiltin:
+			return fmt.Errorf("rule %s is built in; disable it instead", id)
+		case isFileSource(source):
+			return fmt.Errorf("rule %s is defined in %s; delete it there", id, source)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM governance_rules WHERE rule_id = ?`, id); err != nil {
+			return fmt.Errorf("delete rule %s: %w", id, err)
+		}
+	}
 	return nil
 }
 
-func (r *Runner) EnsureDefaultRules(ctx context.Context) error {
-	return r.UpsertRules(ctx, defaultRules())
+func ruleSource(ctx context.Context, db *sql.DB, id string) (string, error) {
+	var source string
+	err := db.QueryRowContext(ctx, `SELECT source FROM governance_rules WHERE rule_id = ?`, id).Scan(&source)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("unknown rule %q", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read rule %s: %w", id, err)
+	}
+	return source, nil
 }
 
 func (r *Runner) ListRules(ctx context.Context) ([]Rule, error) {
 	if err := r.EnsureDefaultRules(ctx); err != nil {
 		return nil, err
 	}
-	db, err := sql.Open("duckdb", r.duckDBPath)
+	db, release, err := r.open()
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = db.Close() }()
+	defer release()
 
-	rows, err := db.QueryContext(ctx, `SELECT rule_id, category, severity, description, query_sql, enabled FROM governance_rules ORDER BY rule_id`)
+	rows, err := db.QueryContext(ctx, `SELECT rule_id, category, severity, description, query_sql, enabled, source, coalesce(pattern, '') FROM governance_rules ORDER BY rule_id`)
 	if err != nil {
 		return nil, err
 	}
@@ -155,82 +351,108 @@ func (r *Runner) ListRules(ctx context.Context) ([]Rule, error) {
 	out := make([]Rule, 0)
 	for rows.Next() {
 		var r Rule
-		if err := rows.Scan(&r.ID, &r.Category, &r.Severity, &r.Description, &r.QuerySQL, &r.Enabled); err != nil {
+		if err := rows.Scan(&r.ID, &r.Category, &r.Severity, &r.Description, &r.QuerySQL, &r.Enabled, &r.Source, &r.Pattern); err != nil {
 			return nil, err
 		}
+		// Recompile so stored pattern rules follow the current compiler.
+		if query, err := ruleQuery(r); err == nil {
+			r.QuerySQL = query
+		}
 		out = append(out, r)
 	}
 	return out, rows.Err()
 }
 
+// Run executes the enabled rules and returns the violations that no
+// //goastdb:ignore comment suppresses. Rules that fail do not stop the others;
+// their failures are joined into the returned error alongside the violations.
+// Use Check to also see suppressed violations, per-rule timing and problems
+// with the suppressions themselves.
 func (r *Runner) Run(ctx context.Context, opts RunOptions) ([]Violation, error) {
-	rules, err := r.ListRules(ctx)
+	report, err := r.Check(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	return report.Violations, report.Err()
+}
+
+// Check executes the enabled rules and applies inline suppressions.
+func (r *Runner) Check(ctx context.Context, opts RunOptions) (Report, error) {
+	rules, err := r.ListRules(ctx)
+	if err != nil {
+		return Report{}, err
+	}
 	selected := filterRules(rules, opts.RuleIDs)
 
-	db, err := sql.Open("duckdb", r.duckDBPath)
+	db, release, err := r.open()
 	if err != nil {
-		return nil, err
+		return Report{}, err
 	}
-	defer func() { _ = db.Close() }()
+	defer release()
 
-	out := make([]Violation, 0)
-	for _, rule := range selected {
-		if !rule.Enabled {
+	out, stats := runRules(ctx, db, selected, opts)
+	if err := resolveLocations(ctx, db, out); err != nil {
+		return Report{}, err
+	}
+	suppressions, err := loadSuppressions(ctx, db)
+	if err != nil {
+		return Report{}, err
+	}
+	report := applySuppressions(out, suppressions, rules, selected, stats)
+	report.Stats = stats
+	return report, nil
+}
+
+// resolveLocations fills the position of violations whose rule returned the
+// file_id and ordinal of a node, keeping any file_path or line the rule set.
+func resolveLocations(ctx context.Context, db *sql.DB, violations []Violation) error {
+	var stmt *sql.Stmt
+	for i := range violations {
+		v := &violations[i]
+		fileID, hasFile := v.RawValues["file_id"]
+		ordinal, hasOrdinal := v.RawValues["ordinal"]
+		if !hasFile || !hasOrdinal || fileID == nil || ordinal == nil {
 			continue
 		}
-		rows, err := db.QueryContext(ctx, rule.QuerySQL)
-		if err != nil {
-			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		if stmt == nil {
+			var err error
+			stmt, err = db.PrepareContext(ctx, `
+SELECT f.path, n.start_line, n.start_col, n.end_line, n.end_col
+FROM nodes n
+JOIN files f ON f.file_id = n.file_id
+WHERE n.file_id = ? AND n.ordinal = ?`)
+			if err != nil {
+				return fmt.Errorf("resolve violation locations: %w", err)
+			}
+			defer func() { _ = stmt.Close() }()
+		}
+		var path string
+		var line, col, endLine, endCol int
+		err := stmt.QueryRowContext(ctx, asInt(fileID), asInt(ordinal)).Scan(&path, &line, &col, &endLine, &endCol)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
 		}
-		cols, err := rows.Columns()
 		if err != nil {
-			_ = rows.Close()
-			return nil, err
+			return fmt.Errorf("rule %s: resolve node location: %w", v.RuleID, err)
 		}
-		for rows.Next() {
-			vals := make([]any, len(cols))
-			ptrs := make([]any, len(cols))
-			for i := range vals {
-				ptrs[i] = &vals[i]
-			}
-			if err := rows.Scan(ptrs...); err != nil {
-				_ = rows.Close()
-				return nil, err
-			}
-			raw := make(map[string]any, len(cols))
-			for i, col := range cols {
-				raw[col] = normalize(vals[i])
-			}
-			out = append(out, Violation{
-				RuleID:    rule.ID,
-				Category:  rule.Category,
-				Severity:  rule.Severity,
-				FilePath:  asString(raw["file_path"]),
-				Symbol:    asString(raw["symbol"]),
-				Detail:    asString(raw["detail"]),
-				Line:      asInt(raw["line"]),
-				RawValues: raw,
-			})
+		if v.FilePath == "" {
+			v.FilePath = path
 		}
-		if err := rows.Err(); err != nil {
-			_ = rows.Close()
-			return nil, err
+		if v.Line == 0 {
+			v.Line, v.Column, v.EndLine, v.EndColumn = line, col, endLine, endCol
+		} else if v.Line == line && v.Column == 0 {
+			v.Column, v.EndLine, v.EndColumn = col, endLine, endCol
 		}
-		_ = rows.Close()
 	}
-
-	return out, nil
+	return nil
 }
 
 func (r *Runner) AdhocQuery(ctx context.Context, query string, args ...any) ([]Row, error) {
-	db, err := sql.Open("duckdb", r.duckDBPath)
+	db, release, err := r.open()
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = db.Close() }()
+	defer release()
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -261,12 +483,27 @@ func (r *Runner) AdhocQuery(ctx context.Context, query string, args ...any) ([]R
 	return out, rows.Err()
 }
 
+// QuerySelect runs query like QueryTable once it has checked that query is a
+// single SELECT, so SQL from untrusted callers cannot change the database.
+func (r *Runner) QuerySelect(ctx context.Context, query string, args ...any) (Table, error) {
+	db, release, err := r.open()
+	if err != nil {
+		return Table{}, err
+	}
+	err = checkSingleSelect(ctx, db, query)
+	release()
+	if err != nil {
+		return Table{}, err
+	}
+	return r.QueryTable(ctx, query, args...)
+}
+
 func (r *Runner) QueryTable(ctx context.Context, query string, args ...any) (Table, error) {
-	db, err := sql.Open("duckdb", r.duckDBPath)
+	db, release, err := r.open()
 	if err != nil {
 		return Table{}, err
 	}
-	defer func() { _ = db.Close() }()
+	defer release()
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -278,7 +515,15 @@ func (r *Runner) QueryTable(ctx context.Context, query string, args ...any) (Tab
 	if err != nil {
 		return Table{}, err
 	}
-	out := Table{Columns: cols, Rows: make([][]any, 0)}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return Table{}, err
+	}
+	types := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		types[i] = ct.DatabaseTypeName()
+	}
+	out := Table{Columns: cols, Types: types, Rows: make([][]any, 0)}
 
 	for rows.Next() {
 		vals := make([]any, len(cols))
@@ -350,6 +595,20 @@ func asInt(v any) int {
 		return int(x)
 	case int32:
 		return int(x)
+	case int16:
+		return int(x)
+	case int8:
+		return int(x)
+	case uint64:
+		return int(x)
+	case uint32:
+		return int(x)
+	case uint16:
+		return int(x)
+	case uint8:
+		return int(x)
+	case *big.Int:
+		return int(x.Int64())
 	case float64:
 		return int(x)
 	case string:
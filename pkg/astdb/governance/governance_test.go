@@ -2,9 +2,12 @@ package governance
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Yacobolo/goastdb/pkg/astdb"
 )
@@ -82,3 +85,609 @@ func writeFile(t *testing.T, path, content string) {
 		t.Fatalf("write file: %v", err)
 	}
 }
+
+func TestRunner_EnableDisableDelete(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	ctx := context.Background()
+	runner := NewRunner(dbPath)
+	builtin := defaultRules()[0].ID
+	if err := runner.SetRuleEnabled(ctx, true, builtin); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list rules: %v", err)
+	}
+	if len(rules) != 1 || !rules[0].Enabled {
+		t.Fatalf("expected enabled built-in rule to survive EnsureDefaultRules, got %+v", rules)
+	}
+	if err := runner.DeleteRule(ctx, builtin); err == nil {
+		t.Fatal("expected error removing a built-in rule")
+	}
+	if err := runner.SetRuleEnabled(ctx, false, "MISSING"); err == nil {
+		t.Fatal("expected error for unknown rule")
+	}
+
+	custom := Rule{ID: "CUSTOM", Category: "c", Severity: "error", Description: "d", QuerySQL: "SELECT path AS file_path FROM files WHERE false", Enabled: true}
+	if err := runner.UpsertRules(ctx, []Rule{custom}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := runner.DeleteRule(ctx, custom.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if rules, _ := runner.ListRules(ctx); len(rules) != 1 {
+		t.Fatalf("expected custom rule removed, got %d rules", len(rules))
+	}
+}
+
+func TestRunner_EnsureDefaultRulesRefreshesBuiltins(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	ctx := context.Background()
+	runner := NewRunner(dbPath)
+	builtin := defaultRules()[0]
+	stale := builtin
+	stale.Description = "stale description from an older release"
+	stale.Enabled = !builtin.Enabled
+	stale.Source = SourceBuiltin
+	if err := runner.UpsertRules(ctx, []Rule{stale}); err != nil {
+		t.Fatalf("upsert stale built-in: %v", err)
+	}
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list rules: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Description != builtin.Description || rules[0].Enabled != stale.Enabled {
+		t.Fatalf("expected the built-in refreshed with its enabled flag kept, got %+v", rules)
+	}
+
+	override := builtin
+	override.Description = "team override"
+	override.QuerySQL = "SELECT path AS file_path FROM files WHERE false"
+	if err := runner.UpsertRules(ctx, []Rule{override}); err != nil {
+		t.Fatalf("upsert override: %v", err)
+	}
+	if rules, _ := runner.ListRules(ctx); len(rules) != 1 || rules[0].Description != override.Description {
+		t.Fatalf("expected a rule overriding a built-in ID to be kept, got %+v", rules)
+	}
+}
+
+func TestRunner_SyncRuleFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() { panic(\"boom\") }\n")
+	rulesPath := filepath.Join(root, ".goast", "rules", "reliability.yaml")
+	writeFile(t, rulesPath, `rules:
+  - id: NO_PANIC
+    category: reliability
+    severity: error
+    description: no panic
+    sql: |
+      SELECT path AS file_path, 'panic' AS symbol, 'panic call' AS detail, start_line AS line
+      FROM call_sites WHERE callee = 'panic'
+  - id: DISABLED_RULE
+    category: style
+    severity: info
+    description: off by default
+    enabled: false
+    sql: SELECT path AS file_path FROM files WHERE false
+`)
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	ctx := context.Background()
+	runner := NewRunner(dbPath)
+	if err := runner.SyncRuleFiles(ctx, root); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	violations, err := runner.Run(ctx, RunOptions{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(violations) != 1 || violations[0].RuleID != "NO_PANIC" || violations[0].Line != 3 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	if err := runner.SetRuleEnabled(ctx, false, "NO_PANIC"); err == nil {
+		t.Fatal("expected error toggling a file-defined rule")
+	}
+
+	writeFile(t, rulesPath, "id: NO_PANIC\ncategory: reliability\nseverity: warning\ndescription: no panic\nsql: SELECT path AS file_path FROM files WHERE false\n")
+	if err := runner.SyncRuleFiles(ctx, root); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	sources := make(map[string]string, len(rules))
+	for _, r := range rules {
+		sources[r.ID] = r.Source
+	}
+	if _, ok := sources["DISABLED_RULE"]; ok {
+		t.Fatal("expected DISABLED_RULE to be pruned")
+	}
+	if sources["NO_PANIC"] != ".goast/rules/reliability.yaml" || sources["EXAMPLE_IMPORTS_INTERNAL_ONLY"] != SourceBuiltin {
+		t.Fatalf("unexpected sources: %v", sources)
+	}
+
+	writeFile(t, rulesPath, "id: NO_PANIC\ncategory: reliability\nseverity: fatal\ndescription: no panic\nsql: SELECT 1\n")
+	err = runner.SyncRuleFiles(ctx, root)
+	if err == nil || !strings.Contains(err.Error(), ".goast/rules/reliability.yaml:1:") || !strings.Contains(err.Error(), "invalid severity") {
+		t.Fatalf("expected located validation error, got %v", err)
+	}
+
+	// A rule that fails its contract names its line and prunes nothing.
+	writeFile(t, rulesPath, "rules:\n  - id: BAD_CONTRACT\n    category: style\n    severity: info\n    description: no file_path\n    sql: SELECT 1 AS x\n")
+	err = runner.SyncRuleFiles(ctx, root)
+	if err == nil || !strings.Contains(err.Error(), ".goast/rules/reliability.yaml:2: rule BAD_CONTRACT") {
+		t.Fatalf("expected located contract error, got %v", err)
+	}
+	rules, err = runner.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	kept := false
+	for _, r := range rules {
+		kept = kept || r.ID == "NO_PANIC"
+	}
+	if !kept {
+		t.Fatal("a failed sync must not prune existing file rules")
+	}
+}
+
+func TestRunner_RulesSurviveRebuild(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := NewRunner(dbPath)
+	rule := Rule{ID: "CUSTOM", Category: "c", Severity: "error", Description: "d", QuerySQL: "SELECT path AS file_path FROM files WHERE false", Enabled: true}
+	if err := runner.UpsertRules(ctx, []Rule{rule}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if _, err := runner.AdhocQuery(ctx, `CREATE TABLE notes AS SELECT 'keep me' AS note`); err != nil {
+		t.Fatalf("create user table: %v", err)
+	}
+
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n\nfunc helper() {}\n")
+	res, err := astdb.Run(ctx, opts)
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if res.Sync.Action != "rebuild" {
+		t.Fatalf("expected rebuild, got %q", res.Sync.Action)
+	}
+
+	rules, err := runner.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list rules: %v", err)
+	}
+	found := false
+	for _, r := range rules {
+		found = found || r.ID == rule.ID
+	}
+	if !found {
+		t.Fatalf("rule %s lost across rebuild: %+v", rule.ID, rules)
+	}
+	rows, err := runner.AdhocQuery(ctx, `SELECT (SELECT note FROM notes) AS note, (SELECT COUNT(*) FROM functions) AS funcs`)
+	if err != nil {
+		t.Fatalf("query after rebuild: %v", err)
+	}
+	if rows[0]["note"] != "keep me" || asInt(rows[0]["funcs"]) != 2 {
+		t.Fatalf("unexpected state after rebuild: %v", rows[0])
+	}
+}
+
+func TestRunner_CheckAppliesSuppressions(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), `package main
+
+func trailing() {
+	panic("a") //goastdb:ignore PANIC_CALL unreachable by construction
+}
+
+// declared explains itself.
+//
+//goastdb:ignore PANIC_CALL startup must abort
+func declared() {
+	if true {
+		panic("b")
+	}
+}
+
+func above() {
+	//goastdb:ignore PANIC_CALL
+	panic("c")
+	panic("d")
+}
+
+func stale() {} //goastdb:ignore PANIC_CALL,NOT_A_RULE nothing to hide
+`)
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := NewRunner(dbPath)
+	rule := Rule{
+		ID:          "PANIC_CALL",
+		Category:    "reliability",
+		Severity:    "error",
+		Description: "panic call",
+		Enabled:     true,
+		QuerySQL:    `SELECT path AS file_path, func_name(file_id, ordinal) AS symbol, 'panic' AS detail, start_line AS line FROM call_sites WHERE callee = 'panic'`,
+	}
+	if err := runner.UpsertRules(ctx, []Rule{rule}); err != nil {
+		t.Fatalf("upsert rule: %v", err)
+	}
+	report, err := runner.Check(ctx, RunOptions{RuleIDs: []string{rule.ID}})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Line != 19 {
+		t.Fatalf("expected only the panic on line 19 to remain, got %+v", report.Violations)
+	}
+	if len(report.Suppressed) != 3 {
+		t.Fatalf("expected 3 suppressed violations, got %+v", report.Suppressed)
+	}
+
+	got := make([]string, 0, len(report.Problems))
+	for _, p := range report.Problems {
+		got = append(got, fmt.Sprintf("%d %s %s", p.Line, p.RuleID, p.Problem))
+	}
+	want := []string{
+		"17 PANIC_CALL " + ProblemMissingReason,
+		"22 PANIC_CALL " + ProblemStale,
+		"22 NOT_A_RULE " + ProblemUnknownRule,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected problems:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestRunner_RecordRunAndTrend(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	src := filepath.Join(root, "main.go")
+	writeFile(t, src, "package main\n\nfunc a() { panic(1) }\nfunc b() { panic(2) }\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+
+	runner := NewRunner(dbPath)
+	if _, err := runner.Trend(ctx, TrendOptions{}); err == nil {
+		t.Fatal("expected an error before any run was recorded")
+	}
+	rule := Rule{
+		ID:          "PANIC_CALL",
+		Category:    "reliability",
+		Severity:    "error",
+		Description: "panic call",
+		Enabled:     true,
+		QuerySQL:    `SELECT path AS file_path, 'panic' AS detail, start_line AS line FROM call_sites WHERE callee = 'panic'`,
+	}
+	for i, body := range []string{"", "package main\n\nfunc a() { panic(1) }\n"} {
+		if body != "" {
+			writeFile(t, src, body)
+		}
+		if _, err := astdb.Run(ctx, opts); err != nil {
+			t.Fatalf("build ast db: %v", err)
+		}
+		if err := runner.UpsertRules(ctx, []Rule{rule}); err != nil {
+			t.Fatalf("upsert rule: %v", err)
+		}
+		report, err := runner.Check(ctx, RunOptions{RuleIDs: []string{rule.ID}})
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		run, err := runner.RecordRun(ctx, report, "0123456789abcdef")
+		if err != nil {
+			t.Fatalf("record run: %v", err)
+		}
+		if run.ID != int64(i+1) || run.Violations != 2-i {
+			t.Fatalf("unexpected run %+v", run)
+		}
+	}
+
+	trend, err := runner.Trend(ctx, TrendOptions{By: "category"})
+	if err != nil {
+		t.Fatalf("trend: %v", err)
+	}
+	if len(trend.Columns) != 4 || !strings.HasSuffix(trend.Columns[1], " 0123456") {
+		t.Fatalf("unexpected columns %v", trend.Columns)
+	}
+	want := [][]any{{"reliability", int64(2), int64(1), int64(-1)}, {"(total)", int64(2), int64(1), int64(-1)}}
+	if fmt.Sprint(trend.Rows) != fmt.Sprint(want) {
+		t.Fatalf("trend rows = %v, want %v", trend.Rows, want)
+	}
+}
+
+func TestRunner_ValidateRuleSQL(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() { panic(1) }\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+	runner := NewRunner(dbPath)
+
+	rejected := map[string]string{
+		"SELECT path AS file_path FROM files; DROP TABLE nodes": "single read-only SELECT",
+		"DELETE FROM nodes":                                "single read-only SELECT",
+		"SELEC path FROM files":                            "syntax error",
+		"SELECT path FROM files":                           "must include file_path",
+		"SELECT path AS file_path, 'x' AS line FROM files": "line must be an integer",
+		"SELECT path AS file_path FROM no_such_table":      "does not bind",
+	}
+	for query, want := range rejected {
+		_, err := runner.ValidateRuleSQL(ctx, Rule{ID: "R", QuerySQL: query})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateRuleSQL(%q) error = %v, want %q", query, err, want)
+		}
+	}
+	if rows, err := runner.AdhocQuery(ctx, `SELECT count(*) AS n FROM nodes`); err != nil || len(rows) != 1 {
+		t.Fatalf("nodes table damaged by validation: %v", err)
+	}
+
+	warnings, err := runner.ValidateRuleSQL(ctx, Rule{ID: "R", QuerySQL: `SELECT file_id, ordinal, 'panic' AS detial FROM call_sites`})
+	if err != nil {
+		t.Fatalf("node-located rule rejected: %v", err)
+	}
+	want := []string{
+		"no detail column; findings will have an empty message",
+		"column detial looks like a misspelling of detail; it will only appear in raw values",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("warnings = %q, want %q", warnings, want)
+	}
+
+	err = runner.UpsertRules(ctx, []Rule{{ID: "BAD", Category: "c", Severity: "error", Description: "d", QuerySQL: "SELECT 1", Enabled: true}})
+	if err == nil || !strings.Contains(err.Error(), "rule BAD") {
+		t.Fatalf("expected upsert to reject a rule without a location, got %v", err)
+	}
+}
+
+func TestRunner_CheckIsolatesFailingRules(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() { panic(1) }\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := NewRunner(dbPath)
+	rules := []Rule{
+		{ID: "A_PANIC", QuerySQL: `SELECT path AS file_path, 'panic' AS detail, start_line AS line FROM call_sites WHERE callee = 'panic'`},
+		{ID: "B_BROKEN", QuerySQL: `SELECT path AS file_path, CAST('not a number' AS INTEGER) AS line FROM files`},
+		{ID: "C_SLOW", QuerySQL: `SELECT 'main.go' AS file_path FROM range(10000000000) r(i) WHERE i % 1000000007 = 1000000006`},
+	}
+	for i := range rules {
+		rules[i].Category, rules[i].Severity, rules[i].Description, rules[i].Enabled = "c", "error", "d", true
+	}
+	if err := runner.UpsertRules(ctx, rules); err != nil {
+		t.Fatalf("upsert rules: %v", err)
+	}
+
+	report, err := runner.Check(ctx, RunOptions{RuleIDs: []string{"A_PANIC", "B_BROKEN", "C_SLOW"}, Parallelism: 2, RuleTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].RuleID != "A_PANIC" {
+		t.Fatalf("expected the healthy rule's violation, got %+v", report.Violations)
+	}
+	if len(report.Stats) != 3 || report.Stats[0].Rows != 1 || report.Stats[0].Elapsed <= 0 {
+		t.Fatalf("unexpected stats %+v", report.Stats)
+	}
+	failures := report.Failures()
+	if len(failures) != 2 || failures[0].RuleID != "B_BROKEN" || failures[0].TimedOut || !failures[1].TimedOut {
+		t.Fatalf("unexpected failures %+v", failures)
+	}
+	if _, err := runner.Run(ctx, RunOptions{RuleIDs: []string{"B_BROKEN"}}); err == nil || !strings.Contains(err.Error(), "rule B_BROKEN") {
+		t.Fatalf("expected Run to report the failed rule, got %v", err)
+	}
+}
+
+func TestRunner_PlanFixes(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "a.go"), "package a\n\nfunc F(x interface{}) interface{} { return x }\n")
+	writeFile(t, filepath.Join(root, "b.go"), "package a\n\nvar B interface{}\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := NewRunner(dbPath)
+	rules := []Rule{
+		{ID: "USE_ANY", QuerySQL: `
+SELECT f.path AS file_path, n.start_line AS line, n.start_offset AS fix_start_offset, n.end_offset AS fix_end_offset, 'any' AS fix_text
+FROM nodes n JOIN files f USING (file_id)
+WHERE n.kind = '*ast.InterfaceType'`},
+		// Rewrites the whole signature, overlapping both USE_ANY fixes in a.go.
+		{ID: "RENAME_F", QuerySQL: `
+SELECT path AS file_path, start_line AS line, start_offset AS fix_start_offset, start_offset + 34 AS fix_end_offset, 'func G(x any) any' AS fix_text
+FROM func_decls WHERE name = 'F'`},
+		{ID: "HALF_FIX", QuerySQL: `SELECT path AS file_path, 1 AS fix_start_offset FROM files`},
+	}
+	for i := range rules {
+		rules[i].Category, rules[i].Severity, rules[i].Description, rules[i].Enabled = "c", "error", "d", true
+	}
+	if err := runner.UpsertRules(ctx, rules[2:]); err == nil || !strings.Contains(err.Error(), "fix_end_offset") {
+		t.Fatalf("expected an incomplete fix to be rejected, got %v", err)
+	}
+	if err := runner.UpsertRules(ctx, rules[:2]); err != nil {
+		t.Fatalf("upsert rules: %v", err)
+	}
+	violations, err := runner.Run(ctx, RunOptions{RuleIDs: []string{"USE_ANY", "RENAME_F"}})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(violations) != 4 {
+		t.Fatalf("expected 4 violations, got %+v", violations)
+	}
+
+	// b.go changes after indexing, so its fix must be refused.
+	writeFile(t, filepath.Join(root, "b.go"), "package a\n\nvar B, C interface{}\n")
+	plans, err := runner.PlanFixes(ctx, root, violations)
+	if err != nil {
+		t.Fatalf("plan fixes: %v", err)
+	}
+	if len(plans) != 2 || plans[0].FilePath != "a.go" || plans[1].FilePath != "b.go" {
+		t.Fatalf("unexpected plans %+v", plans)
+	}
+	a := plans[0]
+	if a.Err != nil {
+		t.Fatalf("a.go: %v", a.Err)
+	}
+	if want := "package a\n\nfunc G(x any) any { return x }\n"; string(a.After) != want {
+		t.Fatalf("a.go fixed to %q, want %q", a.After, want)
+	}
+	// The earliest fix wins; the ones inside it are skipped.
+	if len(a.Applied) != 1 || a.Applied[0].RuleID != "RENAME_F" || len(a.Skipped) != 2 || a.Skipped[0].Reason != "overlaps another fix" {
+		t.Fatalf("expected USE_ANY to be skipped as overlapping, got applied %+v skipped %+v", a.Applied, a.Skipped)
+	}
+	if plans[1].Err == nil || !strings.Contains(plans[1].Err.Error(), "changed since it was indexed") {
+		t.Fatalf("expected stale b.go to be refused, got %v", plans[1].Err)
+	}
+}
+
+func TestRunner_PatternRule(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc check(a int) bool {\n\treturn a == a || a == 1\n}\n")
+	writeFile(t, filepath.Join(root, ".goast", "rules", "self.yaml"), "id: SELF_COMPARE\ncategory: correctness\nseverity: error\ndescription: compares a value with itself\npattern: $x == $x\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	runner := NewRunner(dbPath)
+	if err := runner.SyncRuleFiles(ctx, root); err != nil {
+		t.Fatalf("sync rule files: %v", err)
+	}
+	violations, err := runner.Run(ctx, RunOptions{RuleIDs: []string{"SELF_COMPARE"}})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("expected one violation, got %+v", violations)
+	}
+	v := violations[0]
+	if v.FilePath != "main.go" || v.Line != 4 || v.Column != 9 || v.Symbol != "check" || v.Detail != "compares a value with itself" {
+		t.Fatalf("unexpected violation %+v", v)
+	}
+
+	both := Rule{ID: "BOTH", Category: "c", Severity: "error", Description: "d", QuerySQL: "SELECT 1", Pattern: "panic($_)"}
+	if err := ValidateRule(both); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Fatalf("expected a rule with sql and pattern to be rejected, got %v", err)
+	}
+}
+
+func TestDefaultRules_Contract(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	ctx := context.Background()
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+	runner := NewRunner(dbPath)
+	for _, rule := range defaultRules() {
+		warnings, err := runner.ValidateRuleSQL(ctx, rule)
+		if err != nil {
+			t.Fatalf("built-in rule %s: %v", rule.ID, err)
+		}
+		if len(warnings) != 0 {
+			t.Fatalf("built-in rule %s: unexpected warnings %v", rule.ID, warnings)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package governance
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	duckdb "github.com/duckdb/duckdb-go/v2"
+)
+
+// historyTables hold recorded check runs. Like governance_rules they are not
+// index tables, so rebuilds keep them.
+var historyTables = []string{
+	`CREATE TABLE IF NOT EXISTS violation_runs (run_id BIGINT PRIMARY KEY, run_unix BIGINT NOT NULL, commit_sha TEXT NOT NULL DEFAULT '', violations INTEGER NOT NULL, suppressed INTEGER NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS violation_run_rules (run_id BIGINT NOT NULL, rule_id TEXT NOT NULL, category TEXT NOT NULL, severity TEXT NOT NULL, violations INTEGER NOT NULL, elapsed_ms DOUBLE, error TEXT, PRIMARY KEY(run_id, rule_id))`,
+	`ALTER TABLE violation_run_rules ADD COLUMN IF NOT EXISTS elapsed_ms DOUBLE`,
+	`ALTER TABLE violation_run_rules ADD COLUMN IF NOT EXISTS error TEXT`,
+	`CREATE TABLE IF NOT EXISTS violations (run_id BIGINT NOT NULL, rule_id TEXT NOT NULL, category TEXT NOT NULL, severity TEXT NOT NULL, file_path TEXT, symbol TEXT, detail TEXT, line INTEGER, fingerprint TEXT NOT NULL)`,
+}
+
+var errNoHistory = errors.New("no recorded check runs; run goastdb check first")
+
+// HistoryRun is one recorded check run.
+type HistoryRun struct {
+	ID         int64  `json:"run_id"`
+	Unix       int64  `json:"run_unix"`
+	Commit     string `json:"commit,omitempty"`
+	Violations int    `json:"violations"`
+	Suppressed int    `json:"suppressed"`
+}
+
+// TrendOptions selects what Trend reports.
+type TrendOptions struct {
+	// By is "rule" (default) or "category".
+	By string
+	// Runs limits the report to the most recent runs; 0 means 10.
+	Runs int
+}
+
+// RecordRun stores report as a new check run: its unsuppressed violations and
+// a count, elapsed time and any error for every rule that ran, including rules
+// that found nothing, so trends reach zero. commit may be empty.
+func (r *Runner) RecordRun(ctx context.Context, report Report, commit string) (HistoryRun, error) {
+	db, release, err := r.open()
+	if err != nil {
+		return HistoryRun{}, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return HistoryRun{}, fmt.Errorf("open conn: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, `BEGIN TRANSACTION`); err != nil {
+		return HistoryRun{}, err
+	}
+	rollback := func(e error) (HistoryRun, error) {
+		_, _ = conn.ExecContext(ctx, `ROLLBACK`)
+		return HistoryRun{}, e
+	}
+	for _, stmt := range historyTables {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return rollback(fmt.Errorf("ensure history tables: %w", err))
+		}
+	}
+
+	run := HistoryRun{Unix: time.Now().Unix(), Commit: commit, Violations: len(report.Violations), Suppressed: len(report.Suppressed)}
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(run_id), 0) + 1 FROM violation_runs`).Scan(&run.ID); err != nil {
+		return rollback(fmt.Errorf("allocate run id: %w", err))
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO violation_runs VALUES (?, ?, ?, ?, ?)`, run.ID, run.Unix, run.Commit, run.Violations, run.Suppressed); err != nil {
+		return rollback(fmt.Errorf("record run: %w", err))
+	}
+
+	counts := make(map[string]int, len(report.Rules))
+	stats := make(map[string]RuleStat, len(report.Stats))
+	for _, stat := range report.Stats {
+		stats[stat.RuleID] = stat
+	}
+	// Large reports make row-at-a-time inserts slow, so rows are appended.
+	err = conn.Raw(func(raw any) error {
+		rawConn, ok := raw.(driver.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected raw conn %T", raw)
+		}
+		va, err := duckdb.NewAppenderFromConn(rawConn, "", "violations")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = va.Close() }()
+		ra, err := duckdb.NewAppenderFromConn(rawConn, "", "violation_run_rules")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = ra.Close() }()
+
+		for _, v := range report.Violations {
+			counts[v.RuleID]++
+			if err := va.AppendRow(run.ID, v.RuleID, v.Category, v.Severity, v.FilePath, v.Symbol, v.Detail, int32(v.Line), Fingerprint(v)); err != nil {
+				return fmt.Errorf("record violation: %w", err)
+			}
+		}
+		for _, rule := range report.Rules {
+			stat := stats[rule.ID]
+			var failure any
+			if stat.Failed() {
+				failure = stat.Error
+			}
+			if err := ra.AppendRow(run.ID, rule.ID, rule.Category, rule.Severity, int32(counts[rule.ID]), float64(stat.Elapsed)/float64(time.Millisecond), failure); err != nil {
+				return fmt.Errorf("record rule count: %w", err)
+			}
+		}
+		if err := va.Flush(); err != nil {
+			return fmt.Errorf("record violations: %w", err)
+		}
+		return ra.Flush()
+	})
+	if err != nil {
+		return rollback(err)
+	}
+	if _, err := conn.ExecContext(ctx, `COMMIT`); err != nil {
+		return rollback(fmt.Errorf("record run: %w", err))
+	}
+	return run, nil
+}
+
+// Trend returns violation counts per rule or category across the most recent
+// runs: one row per key, one column per run (oldest first) and a final change
+// column. A blank cell means the rule did not run or failed. The last row
+// totals each run.
+func (r *Runner) Trend(ctx context.Context, opts TrendOptions) (Table, error) {
+	key := "rule_id"
+	switch opts.By {
+	case "", "rule":
+	case "category":
+		key = "category"
+	default:
+		return Table{}, fmt.Errorf("invalid trend grouping %q (expected rule or category)", opts.By)
+	}
+	if opts.Runs <= 0 {
+		opts.Runs = 10
+	}
+
+	db, release, err := r.open()
+	if err != nil {
+		return Table{}, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+
+	var recorded bool
+	if err := db.QueryRowContext(ctx, `SELECT count(*) > 0 FROM duckdb_tables() WHERE table_name = 'violation_runs'`).Scan(&recorded); err != nil {
+		return Table{}, err
+	}
+	if !recorded {
+		return Table{}, errNoHistory
+	}
+
+	runs := make([]HistoryRun, 0, opts.Runs)
+	rows, err := db.QueryContext(ctx, `
+SELECT run_id, run_unix, commit_sha, violations, suppressed
+FROM (SELECT * FROM violation_runs ORDER BY run_id DESC LIMIT ?)
+ORDER BY run_id`, opts.Runs)
+	if err != nil {
+		return Table{}, fmt.Errorf("read runs: %w", err)
+	}
+	for rows.Next() {
+		var run HistoryRun
+		if err := rows.Scan(&run.ID, &run.Unix, &run.Commit, &run.Violations, &run.Suppressed); err != nil {
+			_ = rows.Close()
+			return Table{}, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Close(); err != nil {
+		return Table{}, err
+	}
+	if len(runs) == 0 {
+		return Table{}, errNoHistory
+	}
+
+	rows, err = db.QueryContext(ctx, fmt.Sprintf(`
+SELECT %[1]s, run_id, SUM(violations)::BIGINT
+FROM violation_run_rules
+WHERE run_id >= ? AND error IS NULL
+GROUP BY %[1]s, run_id
+ORDER BY %[1]s, run_id`, key), runs[0].ID)
+	if err != nil {
+		return Table{}, fmt.Errorf("read trend: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	column := make(map[int64]int, len(runs))
+	for i, run := range runs {
+		column[run.ID] = i + 1
+	}
+	t := Table{Columns: []string{key}}
+	for _, run := range runs {
+		t.Columns = append(t.Columns, runLabel(run))
+	}
+	t.Columns = append(t.Columns, "change")
+
+	totals := make([]any, len(t.Columns))
+	totals[0] = "(total)"
+	var current []any
+	flush := func() {
+		if current != nil {
+			current[len(current)-1] = change(current[1 : len(current)-1])
+			t.Rows = append(t.Rows, current)
+		}
+	}
+	for rows.Next() {
+		var name string
+		var runID, count int64
+		if err := rows.Scan(&name, &runID, &count); err != nil {
+			return Table{}, err
+		}
+		if current == nil || current[0] != name {
+			flush()
+			current = make([]any, len(t.Columns))
+			current[0] = name
+		}
+		i := column[runID]
+		current[i] = count
+		sum, _ := totals[i].(int64)
+		totals[i] = sum + count
+	}
+	if err := rows.Err(); err != nil {
+		return Table{}, err
+	}
+	flush()
+	totals[len(totals)-1] = change(totals[1 : len(totals)-1])
+	t.Rows = append(t.Rows, totals)
+	return t, nil
+}
+
+// runLabel names a run column by its time and, when known, short commit.
+func runLabel(run HistoryRun) string {
+	label := fmt.Sprintf("#%d %s", run.ID, time.Unix(run.Unix, 0).UTC().Format("2006-01-02 15:04"))
+	if run.Commit != "" {
+		commit := run.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		label += " " + commit
+	}
+	return label
+}
+
+// change is the difference between the last and first recorded counts, or
+// nil when no count is known.
+func change(counts []any) any {
+	var first, last int64
+	seen := false
+	for _, c := range counts {
+		n, ok := c.(int64)
+		if !ok {
+			continue
+		}
+		if !seen {
+			first, seen = n, true
+		}
+		last = n
+	}
+	if !seen {
+		return nil
+	}
+	return last - first
+}
@@ -0,0 +1,249 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRulesDir is the repository-relative directory holding rule files.
+const DefaultRulesDir = ".goast/rules"
+
+// SourceBuiltin marks rules compiled into goastdb in governance_rules.source.
+// Rules added through the CLI or UpsertRules have an empty source; rules
+// loaded from files carry the file path.
+const SourceBuiltin = "builtin"
+
+// ruleFileEntry is one rule in a .goast/rules/*.yaml file.
+type ruleFileEntry struct {
+	ID          string `yaml:"id"`
+	Category    string `yaml:"category"`
+	Severity    string `yaml:"severity"`
+	Description string `yaml:"description"`
+	SQL         string `yaml:"sql"`
+	Pattern     string `yaml:"pattern"`
+	Enabled     *bool  `yaml:"enabled"`
+}
+
+var ruleFileKeys = map[string]struct{}{
+	"id": {}, "category": {}, "severity": {}, "description": {}, "sql": {}, "pattern": {}, "enabled": {},
+}
+
+// LoadRuleFiles reads every *.yaml and *.yml file in dir. A file holds one rule
+// mapping, a list of them, or a mapping with a "rules" list:
+//
+//	id: NO_PANIC
+//	category: reliability
+//	severity: error
+//	description: library code must not panic
+//	enabled: true
+//	sql: |
+//	  SELECT path AS file_path, ... FROM call_sites WHERE callee = 'panic'
+//
+// A rule may give a Go pattern instead of sql, such as pattern: panic($_).
+// Enabled defaults to true. Errors name the file and line. A missing dir
+// yields no rules. Sources are reported relative to base when possible.
+func LoadRuleFiles(base, dir string) ([]Rule, error) {
+	paths := make([]string, 0)
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("scan rules dir %s: %w", dir, err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	out := make([]Rule, 0)
+	seen := make(map[string]string)
+	for _, path := range paths {
+		source := path
+		if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, "..") {
+			source = rel
+		}
+		source = filepath.ToSlash(source)
+		rules, lines, err := parseRuleFile(path)
+		var le *lineError
+		if errors.As(err, &le) {
+			return nil, fmt.Errorf("%s:%d: %w", source, le.line, le.err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		for i, rule := range rules {
+			loc := fmt.Sprintf("%s:%d", source, lines[i])
+			if prev, dup := seen[rule.ID]; dup {
+				return nil, fmt.Errorf("%s: rule %s already defined at %s", loc, rule.ID, prev)
+			}
+			seen[rule.ID] = loc
+			rule.Source = source
+			rule.location = loc
+			out = append(out, rule)
+		}
+	}
+	return out, nil
+}
+
+// parseRuleFile returns the rules in path and the line each one starts on.
+func parseRuleFile(path string) ([]Rule, []int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
+
+	root := doc.Content[0]
+	var items []*yaml.Node
+	switch root.Kind {
+	case yaml.SequenceNode:
+		items = root.Content
+	case yaml.MappingNode:
+		items = []*yaml.Node{root}
+		if list := mappingValue(root, "rules"); list != nil {
+			if len(root.Content) != 2 || list.Kind != yaml.SequenceNode {
+				return nil, nil, lineErrorf(root.Line, "\"rules\" must be the only key and hold a list")
+			}
+			items = list.Content
+		}
+	default:
+		return nil, nil, lineErrorf(root.Line, "expected a rule mapping or a list of rules")
+	}
+
+	rules := make([]Rule, 0, len(items))
+	lines := make([]int, 0, len(items))
+	for _, item := range items {
+		if item.Kind != yaml.MappingNode {
+			return nil, nil, lineErrorf(item.Line, "expected a rule mapping")
+		}
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key := item.Content[i]
+			if _, ok := ruleFileKeys[key.Value]; !ok {
+				return nil, nil, lineErrorf(key.Line, "unknown key %q", key.Value)
+			}
+		}
+		var entry ruleFileEntry
+		if err := item.Decode(&entry); err != nil {
+			return nil, nil, err
+		}
+		rule := Rule{
+			ID:          strings.TrimSpace(entry.ID),
+			Category:    strings.TrimSpace(entry.Category),
+			Severity:    strings.ToLower(strings.TrimSpace(entry.Severity)),
+			Description: strings.TrimSpace(entry.Description),
+			QuerySQL:    strings.TrimSpace(entry.SQL),
+			Pattern:     strings.TrimSpace(entry.Pattern),
+			Enabled:     entry.Enabled == nil || *entry.Enabled,
+		}
+		if err := ValidateRule(rule); err != nil {
+			return nil, nil, lineErrorf(item.Line, "%w", err)
+		}
+		rules = append(rules, rule)
+		lines = append(lines, item.Line)
+	}
+	return rules, lines, nil
+}
+
+// lineError locates a rule file error on a line.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string { return fmt.Sprintf("line %d: %v", e.line, e.err) }
+
+func (e *lineError) Unwrap() error { return e.err }
+
+func lineErrorf(line int, format string, args ...any) error {
+	return &lineError{line: line, err: fmt.Errorf(format, args...)}
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// SyncRuleFiles loads the rule files under <repoRoot>/.goast/rules into
+// governance_rules and prunes file-defined rules whose definition is gone.
+// A file rule may not reuse the ID of a built-in or CLI-added rule.
+func (r *Runner) SyncRuleFiles(ctx context.Context, repoRoot string) error {
+	rules, err := LoadRuleFiles(repoRoot, filepath.Join(repoRoot, DefaultRulesDir))
+	if err != nil {
+		return err
+	}
+	if err := r.EnsureDefaultRules(ctx); err != nil {
+		return err
+	}
+	stale, err := r.staleRuleFiles(ctx, rules)
+	if err != nil {
+		return err
+	}
+	return r.writeRules(ctx, rules, true, stale...)
+}
+
+// staleRuleFiles returns the IDs of file-defined rules missing from rules.
+func (r *Runner) staleRuleFiles(ctx context.Context, rules []Rule) ([]string, error) {
+	db, release, err := r.open()
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer release()
+
+	existing := make(map[string]string)
+	rows, err := db.QueryContext(ctx, `SELECT rule_id, source FROM governance_rules`)
+	if err != nil {
+		return nil, fmt.Errorf("read governance_rules: %w", err)
+	}
+	for rows.Next() {
+		var id, source string
+		if err := rows.Scan(&id, &source); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		existing[id] = source
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if source, ok := existing[rule.ID]; ok && !isFileSource(source) {
+			return nil, locateRuleError(rule, fmt.Errorf("rule %s conflicts with a %s rule of the same id", rule.ID, describeSource(source)))
+		}
+		keep[rule.ID] = struct{}{}
+	}
+	var stale []string
+	for id, source := range existing {
+		if _, ok := keep[id]; !ok && isFileSource(source) {
+			stale = append(stale, id)
+		}
+	}
+	return stale, nil
+}
+
+func isFileSource(source string) bool {
+	return source != "" && source != SourceBuiltin
+}
+
+func describeSource(source string) string {
+	if source == SourceBuiltin {
+		return "built-in"
+	}
+	return "CLI-added"
+}
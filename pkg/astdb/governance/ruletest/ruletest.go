@@ -0,0 +1,223 @@
+// Package ruletest runs governance rules against fixture directories and
+// compares their findings with expectations written in the fixtures, in the
+// style of golang.org/x/tools/go/analysis/analysistest.
+//
+// A fixture marks each expected finding with a comment on the reported line:
+//
+//	panic("boom") // want "NO_PANIC"
+//
+// Several quoted rule IDs may follow one want; repeating an ID expects that
+// many findings on the line. Expectations for rules that are not under test
+// are ignored, so one fixture tree can serve a whole rule pack.
+package ruletest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+// Mismatch is an expected finding that did not occur (Want > Got) or a
+// finding nobody expected (Got > Want).
+type Mismatch struct {
+	FilePath string
+	Line     int
+	RuleID   string
+	Want     int
+	Got      int
+	// Detail is the detail of the first unexpected finding.
+	Detail string
+}
+
+func (m Mismatch) String() string {
+	loc := fmt.Sprintf("%s:%d", m.FilePath, m.Line)
+	if m.FilePath == "" {
+		loc = "(no location)"
+	}
+	if m.Got < m.Want {
+		return fmt.Sprintf("%s: missing %s finding (want %d, got %d)", loc, m.RuleID, m.Want, m.Got)
+	}
+	msg := fmt.Sprintf("%s: unexpected %s finding (want %d, got %d)", loc, m.RuleID, m.Want, m.Got)
+	if m.Detail != "" {
+		msg += ": " + m.Detail
+	}
+	return msg
+}
+
+// Result is the outcome of running rules against one fixture directory.
+type Result struct {
+	Violations []governance.Violation
+	Mismatches []Mismatch
+}
+
+// Check indexes dir in memory, runs rules against it and diffs the findings
+// with the fixture's want comments. Paths are relative to dir. Built-in rules
+// may be named by ID alone with an empty QuerySQL and Pattern.
+func Check(ctx context.Context, dir string, rules ...governance.Rule) (Result, error) {
+	if len(rules) == 0 {
+		return Result{}, fmt.Errorf("no rules to test")
+	}
+	db, err := astdb.BuildMemoryIndex(ctx, dir)
+	if err != nil {
+		return Result{}, fmt.Errorf("index fixtures %s: %w", dir, err)
+	}
+	defer func() { _ = db.Close() }()
+
+	runner := governance.NewRunnerFromDB(db)
+	if err := runner.EnsureDefaultRules(ctx); err != nil {
+		return Result{}, err
+	}
+	custom := make([]governance.Rule, 0, len(rules))
+	builtin := make([]string, 0)
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+		if strings.TrimSpace(rule.QuerySQL) == "" && strings.TrimSpace(rule.Pattern) == "" {
+			builtin = append(builtin, rule.ID)
+			continue
+		}
+		// The fixture index is private, so rule files are loaded as plain rules.
+		rule.Enabled = true
+		rule.Source = ""
+		custom = append(custom, rule)
+	}
+	if err := runner.UpsertRules(ctx, custom); err != nil {
+		return Result{}, err
+	}
+	if err := runner.SetRuleEnabled(ctx, true, builtin...); err != nil {
+		return Result{}, err
+	}
+	violations, err := runner.Run(ctx, governance.RunOptions{RuleIDs: ids})
+	if err != nil {
+		return Result{}, err
+	}
+
+	wants, err := readWants(ctx, runner, ids)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Violations: violations, Mismatches: diff(wants, violations)}, nil
+}
+
+// Run is Check for use in tests: every mismatch is reported with t.Errorf.
+func Run(t testing.TB, dir string, rules ...governance.Rule) Result {
+	t.Helper()
+	res, err := Check(context.Background(), dir, rules...)
+	if err != nil {
+		t.Fatalf("ruletest %s: %v", dir, err)
+	}
+	for _, m := range res.Mismatches {
+		t.Errorf("%s", m)
+	}
+	return res
+}
+
+type findingKey struct {
+	path   string
+	line   int
+	ruleID string
+}
+
+// readWants counts the expected findings of the rules under test.
+func readWants(ctx context.Context, runner *governance.Runner, ids []string) (map[findingKey]int, error) {
+	tested := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		tested[id] = struct{}{}
+	}
+	rows, err := runner.AdhocQuery(ctx, `
+SELECT f.path, c.start_line, c.text
+FROM comments c
+JOIN files f ON f.file_id = c.file_id
+WHERE regexp_matches(c.text, '^//\s*want\s')
+ORDER BY f.path, c.start_line`)
+	if err != nil {
+		return nil, fmt.Errorf("read want comments: %w", err)
+	}
+	wants := make(map[findingKey]int)
+	for _, row := range rows {
+		path, _ := row["path"].(string)
+		line := toInt(row["start_line"])
+		text, _ := row["text"].(string)
+		ruleIDs, err := parseWant(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		for _, id := range ruleIDs {
+			if _, ok := tested[id]; ok {
+				wants[findingKey{path: path, line: line, ruleID: id}]++
+			}
+		}
+	}
+	return wants, nil
+}
+
+// parseWant returns the quoted rule IDs of a "// want" comment.
+func parseWant(text string) ([]string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(text, "//")), "want"))
+	ids := make([]string, 0)
+	for rest != "" {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid want comment %q: expected quoted rule IDs", text)
+		}
+		id, _ := strconv.Unquote(quoted)
+		ids = append(ids, id)
+		rest = strings.TrimSpace(rest[len(quoted):])
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("want comment names no rule")
+	}
+	return ids, nil
+}
+
+func diff(wants map[findingKey]int, violations []governance.Violation) []Mismatch {
+	got := make(map[findingKey]int)
+	details := make(map[findingKey]string)
+	for _, v := range violations {
+		k := findingKey{path: v.FilePath, line: v.Line, ruleID: v.RuleID}
+		got[k]++
+		if _, ok := details[k]; !ok {
+			details[k] = v.Detail
+		}
+	}
+	out := make([]Mismatch, 0)
+	for k, n := range wants {
+		if got[k] != n {
+			out = append(out, Mismatch{FilePath: k.path, Line: k.line, RuleID: k.ruleID, Want: n, Got: got[k], Detail: details[k]})
+		}
+	}
+	for k, n := range got {
+		if _, ok := wants[k]; !ok {
+			out = append(out, Mismatch{FilePath: k.path, Line: k.line, RuleID: k.ruleID, Got: n, Detail: details[k]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.RuleID < b.RuleID
+	})
+	return out
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case int32:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
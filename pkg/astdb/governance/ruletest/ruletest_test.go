@@ -0,0 +1,48 @@
+package ruletest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+var panicRule = governance.Rule{
+	ID:          "PANIC_CALL",
+	Category:    "reliability",
+	Severity:    "error",
+	Description: "panic call",
+	QuerySQL:    `SELECT path AS file_path, 'panic' AS detail, start_line AS line FROM call_sites WHERE callee = 'panic'`,
+}
+
+func TestRun_MatchesWantComments(t *testing.T) {
+	t.Parallel()
+
+	res := Run(t, "testdata/panics", panicRule)
+	if len(res.Violations) != 1 {
+		t.Fatalf("expected 1 unsuppressed violation, got %+v", res.Violations)
+	}
+}
+
+func TestCheck_ReportsMissingAndUnexpected(t *testing.T) {
+	t.Parallel()
+
+	offByOne := panicRule
+	offByOne.QuerySQL = `SELECT path AS file_path, 'panic' AS detail, start_line + 1 AS line FROM call_sites WHERE callee = 'panic' AND start_line < 10`
+	res, err := Check(context.Background(), "testdata/panics", offByOne)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	want := []string{
+		"panics.go:5: missing PANIC_CALL finding (want 1, got 0)",
+		"panics.go:6: unexpected PANIC_CALL finding (want 0, got 1): panic",
+	}
+	if len(res.Mismatches) != len(want) {
+		t.Fatalf("expected %d mismatches, got %v", len(want), res.Mismatches)
+	}
+	for i, m := range res.Mismatches {
+		if m.String() != want[i] {
+			t.Fatalf("mismatch %d = %q, want %q", i, m, want[i])
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package panics
+
+func Load(key string) string {
+	if key == "" {
+		panic("empty key") // want "PANIC_CALL"
+	}
+	return key
+}
+
+func Must(err error) {
+	if err != nil {
+		panic(err) //goastdb:ignore PANIC_CALL fixture for suppressed findings
+	}
+}
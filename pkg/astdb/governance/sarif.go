@@ -0,0 +1,224 @@
+package governance
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// violationColumns are the RawValues keys already mapped onto Violation fields;
+// every other column a rule returns goes into the SARIF result properties.
+var violationColumns = map[string]struct{}{
+	"file_path": {}, "symbol": {}, "detail": {}, "line": {}, "column": {}, "end_line": {}, "end_column": {}, "file_id": {}, "ordinal": {},
+	"fix_start_offset": {}, "fix_end_offset": {}, "fix_text": {},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string            `json:"name"`
+	InformationURI string            `json:"informationUri"`
+	Rules          []sarifDescriptor `json:"rules"`
+}
+
+type sarifDescriptor struct {
+	ID                   string         `json:"id"`
+	ShortDescription     sarifMessage   `json:"shortDescription"`
+	DefaultConfiguration sarifConfig    `json:"defaultConfiguration"`
+	Properties           map[string]any `json:"properties"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Fixes               []sarifFix        `json:"fixes,omitempty"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifact      `json:"artifactLocation"`
+	Replacements     []sarifReplacement `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifByteRegion `json:"deletedRegion"`
+	InsertedContent sarifContent    `json:"insertedContent"`
+}
+
+type sarifByteRegion struct {
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+}
+
+type sarifContent struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// WriteSARIF writes violations as a SARIF 2.1.0 log. Every rule becomes a
+// reporting descriptor; rules referenced by a violation but missing from rules
+// get a minimal descriptor. Results keep the order of violations and paths are
+// relative to %SRCROOT%.
+func WriteSARIF(w io.Writer, rules []Rule, violations []Violation) error {
+	descriptors := make([]sarifDescriptor, 0, len(rules))
+	index := make(map[string]int, len(rules))
+	addRule := func(rule Rule) {
+		if _, ok := index[rule.ID]; ok {
+			return
+		}
+		index[rule.ID] = len(descriptors)
+		descriptors = append(descriptors, sarifDescriptor{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfig{Level: sarifLevel(rule.Severity)},
+			Properties: map[string]any{
+				"category": rule.Category,
+				"severity": rule.Severity,
+				"tags":     []string{rule.Category},
+			},
+		})
+	}
+	for _, rule := range rules {
+		addRule(rule)
+	}
+
+	results := make([]sarifResult, 0, len(violations))
+	for _, v := range violations {
+		addRule(Rule{ID: v.RuleID, Category: v.Category, Severity: v.Severity, Description: v.RuleID})
+		res := sarifResult{
+			RuleID:    v.RuleID,
+			RuleIndex: index[v.RuleID],
+			Level:     sarifLevel(v.Severity),
+			Message:   sarifMessage{Text: violationMessage(v)},
+			// The baseline fingerprint lets dashboards track a finding across line shifts.
+			PartialFingerprints: map[string]string{"goastdb/v1": Fingerprint(v)},
+		}
+		if v.FilePath != "" {
+			loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: v.FilePath, URIBaseID: "%SRCROOT%"}}
+			if v.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: v.Line, StartColumn: v.Column, EndLine: v.EndLine, EndColumn: v.EndColumn}
+			}
+			res.Locations = []sarifLocation{{PhysicalLocation: loc}}
+			if v.Fix != nil {
+				res.Fixes = []sarifFix{{
+					Description: sarifMessage{Text: "Apply the " + v.RuleID + " fix"},
+					ArtifactChanges: []sarifArtifactChange{{
+						ArtifactLocation: loc.ArtifactLocation,
+						Replacements: []sarifReplacement{{
+							DeletedRegion:   sarifByteRegion{ByteOffset: v.Fix.StartOffset, ByteLength: v.Fix.EndOffset - v.Fix.StartOffset},
+							InsertedContent: sarifContent{Text: v.Fix.Text},
+						}},
+					}},
+				}}
+			}
+		}
+		props := make(map[string]any)
+		if v.Symbol != "" {
+			props["symbol"] = v.Symbol
+		}
+		for k, val := range v.RawValues {
+			if _, ok := violationColumns[k]; !ok {
+				props[k] = val
+			}
+		}
+		if len(props) > 0 {
+			res.Properties = props
+		}
+		results = append(results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "goastdb",
+				InformationURI: "https://github.com/Yacobolo/goastdb",
+				Rules:          descriptors,
+			}},
+			Results: results,
+		}},
+	})
+}
+
+// sarifLevel maps rule severities onto SARIF result levels.
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "error":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func violationMessage(v Violation) string {
+	switch {
+	case v.Symbol != "" && v.Detail != "":
+		return v.Symbol + ": " + v.Detail
+	case v.Detail != "":
+		return v.Detail
+	case v.Symbol != "":
+		return v.Symbol
+	default:
+		return v.RuleID
+	}
+}
@@ -0,0 +1,90 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestWriteSARIF_Golden(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeFile(t, filepath.Join(root, "pkg", "store", "store.go"), `package store
+
+import "fmt"
+
+func Load(key string) string {
+	if key == "" {
+		panic("empty key")
+	}
+	return fmt.Sprint(key)
+}
+`)
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := astdb.Run(context.Background(), opts); err != nil {
+		t.Fatalf("build ast db: %v", err)
+	}
+
+	rules := []Rule{
+		{
+			ID:          "NO_PANIC",
+			Category:    "reliability",
+			Severity:    "error",
+			Description: "library code must not panic",
+			Enabled:     true,
+			QuerySQL: `
+SELECT file_id, ordinal, func_name(file_id, ordinal) AS symbol, 'panic call' AS detail, arg_count
+FROM call_sites
+WHERE callee = 'panic'`,
+		},
+		{
+			ID:          "FMT_CALLS",
+			Category:    "style",
+			Severity:    "info",
+			Description: "fmt usage",
+			Enabled:     true,
+			QuerySQL: `
+SELECT path AS file_path, callee AS symbol, 'fmt call' AS detail, start_line AS line
+FROM call_sites
+WHERE qualifier = 'fmt'`,
+		},
+	}
+	runner := NewRunner(dbPath)
+	if err := runner.UpsertRules(context.Background(), rules); err != nil {
+		t.Fatalf("upsert rules: %v", err)
+	}
+	violations, err := runner.Run(context.Background(), RunOptions{RuleIDs: []string{"NO_PANIC", "FMT_CALLS"}})
+	if err != nil {
+		t.Fatalf("run rules: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, rules, violations); err != nil {
+		t.Fatalf("write sarif: %v", err)
+	}
+	golden := filepath.Join("testdata", "violations.sarif")
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("SARIF output differs from %s (run with -update to accept):\n%s", golden, buf.String())
+	}
+}
@@ -0,0 +1,18 @@
+package governance
+
+import "strings"
+
+// Severities lists rule severities from least to most severe.
+var Severities = []string{"info", "warning", "error", "critical"}
+
+// SeverityRank orders severities: info is 1 and critical is 4. Unknown
+// severities rank 0.
+func SeverityRank(severity string) int {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	for i, s := range Severities {
+		if s == severity {
+			return i + 1
+		}
+	}
+	return 0
+}
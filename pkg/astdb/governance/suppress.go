@@ -0,0 +1,224 @@
+package governance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IgnoreDirective starts an inline suppression comment:
+//
+//	//goastdb:ignore NO_PANIC,LARGE_FUNCTION reason the check does not apply
+//
+// A trailing comment covers its own line. A comment on its own line covers
+// the next line and, when that line starts a declaration, the whole
+// declaration.
+const IgnoreDirective = "//goastdb:ignore"
+
+// Suppression problems reported by Check.
+const (
+	ProblemMissingReason = "missing reason"
+	ProblemMissingRule   = "missing rule id"
+	ProblemUnknownRule   = "unknown rule"
+	ProblemStale         = "stale: matches no violation"
+)
+
+// Suppression is one //goastdb:ignore comment and the lines it covers.
+type Suppression struct {
+	RuleIDs   []string `json:"rule_ids"`
+	Reason    string   `json:"reason,omitempty"`
+	FilePath  string   `json:"file_path"`
+	Line      int      `json:"line"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+}
+
+// SuppressionProblem is a suppression comment that needs attention.
+type SuppressionProblem struct {
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	RuleID   string `json:"rule_id,omitempty"`
+	Problem  string `json:"problem"`
+}
+
+// Report is the outcome of Check. Suppressed violations are left out of
+// Violations.
+type Report struct {
+	// Rules are the enabled rules that ran.
+	Rules      []Rule               `json:"-"`
+	Violations []Violation          `json:"violations"`
+	Suppressed []Violation          `json:"suppressed,omitempty"`
+	Problems   []SuppressionProblem `json:"suppression_problems,omitempty"`
+	// Stats has one entry per rule that ran, in rule order.
+	Stats []RuleStat `json:"rule_stats,omitempty"`
+}
+
+// Failures returns the stats of rules that errored or timed out.
+func (r Report) Failures() []RuleStat {
+	out := make([]RuleStat, 0)
+	for _, s := range r.Stats {
+		if s.Failed() {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Err joins the failures of all failed rules, or returns nil.
+func (r Report) Err() error {
+	errs := make([]error, 0)
+	for _, s := range r.Failures() {
+		errs = append(errs, fmt.Errorf("rule %s: %s", s.RuleID, s.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// loadSuppressions reads the ignore directives from the comments table and
+// works out the lines each one covers.
+func loadSuppressions(ctx context.Context, db *sql.DB) ([]Suppression, error) {
+	rows, err := db.QueryContext(ctx, `
+WITH grouped AS (
+	SELECT c.*, max(c.end_line) OVER (PARTITION BY c.file_id, c.group_ordinal) AS group_end
+	FROM comments c
+),
+directives AS (
+	SELECT g.file_id, f.path, g.text, g.start_line, g.start_col, g.end_line, g.group_end
+	FROM grouped g
+	JOIN files f ON f.file_id = g.file_id
+	WHERE g.text = $directive OR starts_with(g.text, $directive || ' ') OR starts_with(g.text, $directive || chr(9))
+)
+SELECT
+	d.path,
+	d.text,
+	d.start_line,
+	d.end_line,
+	NOT EXISTS (
+		SELECT 1 FROM nodes n
+		WHERE n.file_id = d.file_id AND n.start_line = d.start_line AND n.start_col < d.start_col
+		  AND n.kind NOT IN ('*ast.File', '*ast.Comment', '*ast.CommentGroup')
+	) AS own_line,
+	d.group_end,
+	(
+		SELECT arg_min(n.end_line, n.ordinal) FROM nodes n
+		WHERE n.file_id = d.file_id AND n.start_line = d.group_end + 1
+		  AND n.kind IN ('*ast.FuncDecl', '*ast.GenDecl', '*ast.TypeSpec', '*ast.ValueSpec', '*ast.Field')
+	) AS decl_end
+FROM directives d
+ORDER BY d.path, d.start_line`, sql.Named("directive", IgnoreDirective))
+	if err != nil {
+		return nil, fmt.Errorf("read suppressions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]Suppression, 0)
+	for rows.Next() {
+		var (
+			s        Suppression
+			text     string
+			endLine  int
+			ownLine  bool
+			groupEnd int
+			declEnd  sql.NullInt64
+		)
+		if err := rows.Scan(&s.FilePath, &text, &s.Line, &endLine, &ownLine, &groupEnd, &declEnd); err != nil {
+			return nil, err
+		}
+		s.RuleIDs, s.Reason = parseIgnoreDirective(text)
+		s.StartLine, s.EndLine = s.Line, endLine
+		if ownLine {
+			s.EndLine = groupEnd + 1
+			if declEnd.Valid && int(declEnd.Int64) > s.EndLine {
+				s.EndLine = int(declEnd.Int64)
+			}
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// parseIgnoreDirective splits "//goastdb:ignore A,B reason..." into rule IDs
+// and reason.
+func parseIgnoreDirective(text string) ([]string, string) {
+	fields := strings.Fields(strings.TrimPrefix(text, IgnoreDirective))
+	if len(fields) == 0 {
+		return nil, ""
+	}
+	ids := make([]string, 0)
+	for _, id := range strings.Split(fields[0], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, strings.Join(fields[1:], " ")
+}
+
+// applySuppressions drops suppressed violations and reports suppressions
+// without a reason, naming unknown rules, or matching nothing. Staleness is
+// only judged for rules that ran successfully.
+func applySuppressions(violations []Violation, suppressions []Suppression, rules, selected []Rule, stats []RuleStat) Report {
+	report := Report{Violations: make([]Violation, 0, len(violations))}
+	for _, rule := range selected {
+		if rule.Enabled {
+			report.Rules = append(report.Rules, rule)
+		}
+	}
+	known := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		known[rule.ID] = struct{}{}
+	}
+	ran := make(map[string]struct{}, len(stats))
+	for _, stat := range stats {
+		if !stat.Failed() {
+			ran[stat.RuleID] = struct{}{}
+		}
+	}
+
+	used := make([]map[string]bool, len(suppressions))
+	for i := range used {
+		used[i] = make(map[string]bool)
+	}
+	for _, v := range violations {
+		suppressed := false
+		for i, s := range suppressions {
+			if s.FilePath != v.FilePath || v.Line < s.StartLine || v.Line > s.EndLine {
+				continue
+			}
+			for _, id := range s.RuleIDs {
+				if id == v.RuleID {
+					used[i][id] = true
+					suppressed = true
+				}
+			}
+		}
+		if suppressed {
+			report.Suppressed = append(report.Suppressed, v)
+		} else {
+			report.Violations = append(report.Violations, v)
+		}
+	}
+
+	for i, s := range suppressions {
+		problem := func(ruleID, msg string) {
+			report.Problems = append(report.Problems, SuppressionProblem{FilePath: s.FilePath, Line: s.Line, RuleID: ruleID, Problem: msg})
+		}
+		if len(s.RuleIDs) == 0 {
+			problem("", ProblemMissingRule)
+			continue
+		}
+		if s.Reason == "" {
+			problem(strings.Join(s.RuleIDs, ","), ProblemMissingReason)
+		}
+		for _, id := range s.RuleIDs {
+			if _, ok := known[id]; !ok {
+				problem(id, ProblemUnknownRule)
+				continue
+			}
+			if _, ok := ran[id]; ok && !used[i][id] {
+				problem(id, ProblemStale)
+			}
+		}
+	}
+	return report
+}
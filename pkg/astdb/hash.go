@@ -0,0 +1,58 @@
+package astdb
+
+import "go/ast"
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// subtreeHasher computes a structural FNV-1a hash of a subtree from each
+// node's kind, operator or keyword, and the field holding it. Identifier
+// names, literal values (but not their kind), comments and positions are
+// left out, so copies of code with renamed variables or changed constants
+// hash alike. Hashes are kept for statements and functions only.
+type subtreeHasher struct {
+	state   uint64
+	keep    bool
+	comment bool
+}
+
+func newSubtreeHasher(n ast.Node, kind string) subtreeHasher {
+	h := subtreeHasher{state: fnvOffset}
+	switch n.(type) {
+	case ast.Stmt, *ast.FuncDecl, *ast.FuncLit:
+		h.keep = true
+	case *ast.CommentGroup, *ast.Comment:
+		h.comment = true
+	}
+	h.write(kind)
+	switch v := n.(type) {
+	case *ast.Ident:
+	case *ast.BasicLit:
+		h.write(v.Kind.String())
+	default:
+		h.write(NodeText(n))
+	}
+	return h
+}
+
+func (h *subtreeHasher) write(s string) {
+	for i := 0; i < len(s); i++ {
+		h.state ^= uint64(s[i])
+		h.state *= fnvPrime
+	}
+	h.state ^= 0xff
+	h.state *= fnvPrime
+}
+
+// child mixes in a finished child subtree, in source order.
+func (h *subtreeHasher) child(field string, sum uint64) {
+	h.write(field)
+	for i := 0; i < 8; i++ {
+		h.state ^= sum >> (8 * i) & 0xff
+		h.state *= fnvPrime
+	}
+}
+
+func (h *subtreeHasher) sum() (uint64, bool) { return h.state, h.keep }
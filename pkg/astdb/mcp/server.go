@@ -0,0 +1,367 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+const (
+	protocolVersion = "2025-06-18"
+	serverName      = "goastdb"
+	serverVersion   = "0.1.0"
+
+	defaultMaxRows      = 200
+	defaultMaxCellRunes = 400
+	defaultMaxBytes     = 64 * 1024
+	defaultMaxSnippet   = 400
+)
+
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type Config struct {
+	RepoRoot     string
+	DuckDBPath   string
+	MaxRows      int
+	MaxCellRunes int
+	MaxBytes     int
+	// HelperDirs are searched for *.sql helpers in addition to <repo>/.goast/helpers.
+	HelperDirs []string
+}
+
+type Server struct {
+	cfg    Config
+	runner *governance.Runner
+
+	mu sync.Mutex
+}
+
+func NewServer(cfg Config) *Server {
+	if strings.TrimSpace(cfg.RepoRoot) == "" {
+		cfg.RepoRoot = "."
+	}
+	if strings.TrimSpace(cfg.DuckDBPath) == "" {
+		cfg.DuckDBPath = filepath.Join(cfg.RepoRoot, ".goast", "ast.db")
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultMaxRows
+	}
+	if cfg.MaxCellRunes <= 0 {
+		cfg.MaxCellRunes = defaultMaxCellRunes
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	return &Server{cfg: cfg, runner: governance.NewRunner(cfg.DuckDBPath)}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes responses
+// to w until r is exhausted or ctx is cancelled (MCP stdio transport).
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		resp, ok := s.handleMessage(ctx, []byte(line))
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("write response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read request: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) handleMessage(ctx context.Context, msg []byte) (response, bool) {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error: "+err.Error()), true
+	}
+	// Notifications carry no id and never get a response.
+	isNotification := len(req.ID) == 0
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if isNotification {
+			return response{}, false
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request"), true
+	}
+
+	var (
+		result any
+		rpcErr *rpcError
+	)
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]any{"tools": toolDefinitions()}
+	case "tools/call":
+		result, rpcErr = s.callTool(ctx, req.Params)
+	default:
+		if strings.HasPrefix(req.Method, "notifications/") {
+			return response{}, false
+		}
+		rpcErr = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+	if isNotification {
+		return response{}, false
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr.Code, rpcErr.Message), true
+	}
+	return response{JSONRPC: "2.0", ID: req.ID, Result: result}, true
+}
+
+func errorResponse(id json.RawMessage, code int, message string) response {
+	return response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+	version := protocolVersion
+	for _, v := range supportedProtocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+		"serverInfo":      map[string]any{"name": serverName, "version": serverVersion},
+		"instructions":    schemaHints,
+	}, nil
+}
+
+type toolCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, *rpcError) {
+	var p toolCallParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	handler, ok := s.tools()[p.Name]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+	args := p.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	out, err := handler(ctx, args)
+	if err != nil {
+		// Tool failures are reported in-band so the agent can read and react.
+		return toolResult{IsError: true, Content: []toolContent{{Type: "text", Text: err.Error()}}}, nil
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return toolResult{IsError: true, Content: []toolContent{{Type: "text", Text: err.Error()}}}, nil
+	}
+	return toolResult{Content: []toolContent{{Type: "text", Text: string(b)}}}, nil
+}
+
+// ensureIndex syncs the AST database before every tool call so agents that
+// edit code between calls never query a stale index. An unchanged tree only
+// costs a directory walk.
+func (s *Server) ensureIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	opts := astdb.DefaultOptions()
+	opts.RepoRoot = s.cfg.RepoRoot
+	opts.DuckDBPath = s.cfg.DuckDBPath
+	opts.Mode = "query"
+	opts.QueryBench = false
+	if _, err := astdb.Run(ctx, opts); err != nil {
+		return err
+	}
+	return s.runner.SyncRuleFiles(ctx, s.cfg.RepoRoot)
+}
+
+type tableOutput struct {
+	Columns   []string `json:"columns"`
+	Rows      [][]any  `json:"rows"`
+	RowCount  int      `json:"row_count"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Note      string   `json:"note,omitempty"`
+}
+
+// boundTable caps rows, cell length and total encoded size so one tool
+// response cannot flood the agent's context window.
+func (s *Server) boundTable(t governance.Table, maxRows int) tableOutput {
+	if maxRows <= 0 || maxRows > s.cfg.MaxRows {
+		maxRows = s.cfg.MaxRows
+	}
+	out := tableOutput{Columns: t.Columns, Rows: make([][]any, 0, min(len(t.Rows), maxRows)), RowCount: len(t.Rows)}
+	size := 0
+	for i, row := range t.Rows {
+		if i >= maxRows {
+			out.Truncated = true
+			break
+		}
+		bounded := make([]any, len(row))
+		for c, v := range row {
+			bounded[c] = s.boundCell(v)
+		}
+		b, _ := json.Marshal(bounded)
+		if size+len(b) > s.cfg.MaxBytes {
+			out.Truncated = true
+			break
+		}
+		size += len(b)
+		out.Rows = append(out.Rows, bounded)
+	}
+	if out.Truncated {
+		out.Note = fmt.Sprintf("showing %d of %d rows; add LIMIT/WHERE clauses or aggregate to narrow the result", len(out.Rows), out.RowCount)
+	}
+	return out
+}
+
+func (s *Server) boundCell(v any) any {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	return truncateRunes(str, s.cfg.MaxCellRunes)
+}
+
+func truncateRunes(s string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxRunes]) + "..."
+}
+
+func (s *Server) queryTable(ctx context.Context, query string, args ...any) (governance.Table, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return governance.Table{}, err
+	}
+	t, err := s.runner.QueryTable(ctx, query, args...)
+	if err != nil {
+		return governance.Table{}, fmt.Errorf("%w\n\n%s", err, schemaHints)
+	}
+	return t, nil
+}
+
+// selectTable is queryTable for SQL written by the agent, which must be a
+// single SELECT so the query tool cannot change the index.
+func (s *Server) selectTable(ctx context.Context, query string) (governance.Table, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return governance.Table{}, err
+	}
+	t, err := s.runner.QuerySelect(ctx, query)
+	if err != nil {
+		return governance.Table{}, fmt.Errorf("%w\n\n%s", err, schemaHints)
+	}
+	return t, nil
+}
+
+// helpers returns the built-in and repository helpers, restricted to ids when given.
+func (s *Server) helpers(ids ...string) ([]explore.Query, error) {
+	dirs := append([]string{filepath.Join(s.cfg.RepoRoot, explore.DefaultHelpersDir)}, s.cfg.HelperDirs...)
+	extra, err := explore.LoadHelpers(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	return explore.SelectQueries(ids, extra...)
+}
+
+func readSnippet(repoRoot, relPath string, start, end, maxLines int) (string, bool, error) {
+	rel := filepath.Clean(filepath.FromSlash(relPath))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, fmt.Errorf("path %q escapes repo root", relPath)
+	}
+	// Reading through an os.Root also refuses symlinks that lead outside it.
+	root, err := os.OpenRoot(repoRoot)
+	if err != nil {
+		return "", false, fmt.Errorf("open repo root: %w", err)
+	}
+	defer func() { _ = root.Close() }()
+	b, err := root.ReadFile(rel)
+	if err != nil {
+		return "", false, fmt.Errorf("read source: %w", err)
+	}
+	if end < 0 {
+		end = len(b)
+	}
+	if start < 0 || end > len(b) || start > end {
+		return "", false, fmt.Errorf("offsets [%d,%d) out of range for %s (%d bytes)", start, end, relPath, len(b))
+	}
+	snippet := string(b[start:end])
+	lines := strings.SplitAfter(snippet, "\n")
+	if maxLines > 0 && len(lines) > maxLines {
+		return strings.Join(lines[:maxLines], ""), true, nil
+	}
+	return snippet, false, nil
+}
@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+func TestServer_ToolsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+
+	srv := NewServer(Config{RepoRoot: root, DuckDBPath: filepath.Join(root, ".goast", "ast.db")})
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"query","arguments":{"sql":"SELECT file_id, ordinal, kind FROM nodes WHERE kind = '*ast.FuncDecl'"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"query","arguments":{"sql":"SELECT * FROM missing_table"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"nope"}`,
+	}, "\n")
+	var out strings.Builder
+	if err := srv.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	responses := decodeResponses(t, out.String())
+	if len(responses) != 5 {
+		t.Fatalf("expected 5 responses (notification has none), got %d: %s", len(responses), out.String())
+	}
+
+	var initResult struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	mustUnmarshal(t, responses[0]["result"], &initResult)
+	if initResult.ProtocolVersion != "2024-11-05" {
+		t.Fatalf("unexpected negotiated protocol: %q", initResult.ProtocolVersion)
+	}
+
+	var list struct {
+		Tools []toolDefinition `json:"tools"`
+	}
+	mustUnmarshal(t, responses[1]["result"], &list)
+	if len(list.Tools) != len(srv.tools()) {
+		t.Fatalf("tools/list returned %d tools, server has %d handlers", len(list.Tools), len(srv.tools()))
+	}
+
+	var call toolResult
+	mustUnmarshal(t, responses[2]["result"], &call)
+	if call.IsError || len(call.Content) != 1 {
+		t.Fatalf("unexpected query tool result: %+v", call)
+	}
+	var table tableOutput
+	if err := json.Unmarshal([]byte(call.Content[0].Text), &table); err != nil {
+		t.Fatalf("decode table: %v", err)
+	}
+	if table.RowCount != 1 {
+		t.Fatalf("expected one FuncDecl, got %d", table.RowCount)
+	}
+
+	var failed toolResult
+	mustUnmarshal(t, responses[3]["result"], &failed)
+	if !failed.IsError || !strings.Contains(failed.Content[0].Text, "Query tips") {
+		t.Fatalf("expected error result with schema hints, got %+v", failed)
+	}
+
+	if _, ok := responses[4]["error"]; !ok {
+		t.Fatalf("expected method-not-found error, got %v", responses[4])
+	}
+}
+
+func TestServer_NodeSource(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	src := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	writeFile(t, filepath.Join(root, "main.go"), src)
+	srv := NewServer(Config{RepoRoot: root})
+
+	tbl, err := srv.queryTable(context.Background(), `SELECT file_id, ordinal FROM nodes WHERE kind = '*ast.FuncDecl'`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	args, _ := json.Marshal(map[string]any{"file_id": tbl.Rows[0][0], "ordinal": tbl.Rows[0][1]})
+	out, err := srv.toolNodeSource(context.Background(), args)
+	if err != nil {
+		t.Fatalf("node_source: %v", err)
+	}
+	b, _ := json.Marshal(out)
+	if !strings.Contains(string(b), `func main() {\n\tprintln(\"hi\")\n}`) {
+		t.Fatalf("unexpected snippet: %s", b)
+	}
+}
+
+func TestReadSnippet_StaysInRepo(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(outside, "secret.go"), "package secret\n")
+	if err := os.Symlink(filepath.Join(outside, "secret.go"), filepath.Join(root, "link.go")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	if src, _, err := readSnippet(root, "main.go", 0, -1, 0); err != nil || src != "package main\n" {
+		t.Fatalf("read main.go: %q, %v", src, err)
+	}
+	for _, rel := range []string{"../secret.go", "link.go"} {
+		if _, _, err := readSnippet(root, rel, 0, -1, 0); err == nil {
+			t.Fatalf("expected %s to be refused", rel)
+		}
+	}
+}
+
+func TestBoundTable_Truncates(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(Config{MaxRows: 2, MaxCellRunes: 3})
+	rows := [][]any{{"abcdef"}, {"b"}, {"c"}}
+	out := srv.boundTable(governance.Table{Columns: []string{"v"}, Rows: rows}, 0)
+	if !out.Truncated || len(out.Rows) != 2 || out.RowCount != 3 {
+		t.Fatalf("unexpected bounding: %+v", out)
+	}
+	if out.Rows[0][0] != "abc..." {
+		t.Fatalf("expected truncated cell, got %v", out.Rows[0][0])
+	}
+}
+
+func decodeResponses(t *testing.T, s string) []map[string]json.RawMessage {
+	t.Helper()
+	var out []map[string]json.RawMessage
+	sc := bufio.NewScanner(strings.NewReader(s))
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("decode response %q: %v", sc.Text(), err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func mustUnmarshal(t *testing.T, raw json.RawMessage, dst any) {
+	t.Helper()
+	if err := json.Unmarshal(raw, dst); err != nil {
+		t.Fatalf("unmarshal %s: %v", raw, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
+
+func TestServer_QueryRefusesWrites(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	srv := NewServer(Config{RepoRoot: root})
+
+	for _, sql := range []string{
+		`DROP TABLE governance_rules`,
+		`DELETE FROM nodes`,
+		`SELECT 1; DROP TABLE nodes`,
+		`COPY nodes TO '` + filepath.Join(root, "out.csv") + `'`,
+	} {
+		args, _ := json.Marshal(map[string]any{"sql": sql})
+		if _, err := srv.toolQuery(context.Background(), args); err == nil {
+			t.Fatalf("expected %q to be refused", sql)
+		}
+	}
+	tbl, err := srv.queryTable(context.Background(), `SELECT COUNT(*) FROM nodes`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if tbl.Rows[0][0] == int64(0) {
+		t.Fatal("refused statements must not change the index")
+	}
+	args, _ := json.Marshal(map[string]any{"sql": `SELECT COUNT(*) AS n FROM governance_rules`})
+	if _, err := srv.toolQuery(context.Background(), args); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+}
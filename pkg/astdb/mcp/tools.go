@@ -0,0 +1,361 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb/explore"
+	"github.com/Yacobolo/goastdb/pkg/astdb/governance"
+)
+
+const schemaHints = `goastdb exposes the Go AST of the repository as DuckDB tables.
+
+Tables:
+- files(file_id, path, pkg_name, parse_error, bytes, content_hash): one row per .go file; path is repo-relative with forward slashes.
+- nodes(file_id, ordinal, parent_ordinal, kind, node_text, pos, "end", start_line, start_col, end_line, end_col, start_offset, end_offset, field, field_index, subtree_size, subtree_hash): one row per AST node in pre-order; a node's subtree is ordinals [ordinal, ordinal + subtree_size); subtree_hash, set on statements and functions, is equal for code with the same structure up to identifier names and literal values (the DUPLICATE_CODE helper groups clones); field names the parent's struct field holding it (Fun, Args, X, Body, ...) and field_index its position in a list field. node_text holds identifier names, literal values, import paths, and the operator, token or keyword of several node kinds (see Query tips).
+- comments(file_id, ordinal, group_ordinal, text, start_line, start_col, end_line, end_col): every comment with its raw text (including // or /*); adjacent comments share group_ordinal.
+- function_metrics(file_id, ordinal, kind, name, cyclomatic, cognitive, max_nesting, statements, params, results, returns, loc, sloc, halstead_distinct_operators, halstead_distinct_operands, halstead_operators, halstead_operands, halstead_volume, halstead_difficulty, halstead_effort): one row per *ast.FuncDecl and *ast.FuncLit node; literals are named like Run.func1 and are excluded from their enclosing function's metrics except loc/sloc.
+- run_meta(key, value): index metadata (schema_version, source_fingerprint, updated_unix).
+- governance_rules(rule_id, category, severity, description, query_sql, enabled, updated_unix, source, pattern).
+
+Views (prefer these over raw node joins):
+- func_decls(file_id, ordinal, path, pkg_name, name, receiver, is_method, is_exported, start_line, end_line, line_span, start_offset, end_offset, type_ordinal, body_ordinal); functions and methods are its subsets.
+- type_decls(file_id, ordinal, path, pkg_name, name, type_kind, ...); struct_types adds field_count, interfaces adds method_count.
+- call_sites(file_id, ordinal, path, pkg_name, qualifier, callee_name, callee, fun_kind, arg_count, start_line, ...): callee is 'fmt.Println' or 'panic'.
+- import_paths(file_id, ordinal, path, pkg_name, import_path, alias, is_stdlib, line).
+- package_symbols(file_id, ordinal, name_ordinal, path, pkg_name, pkg_dir, symbol_kind, name, receiver, is_exported, is_test_file, start_line, end_line, subtree_size): one row per package-level func, method, type, const or var name; ordinal is the declaration, name_ordinal its name Ident, and pkg_dir plus pkg_name identify the package (the DEAD_CODE helper reports unreferenced ones).
+
+Macros:
+- children(file_id, ordinal), descendants(file_id, ordinal), ancestors(file_id, ordinal) are table functions returning node rows (ancestors adds depth).
+- func_name(file_id, ordinal) returns the name of the innermost function declaration enclosing a node.
+
+Query tips:
+- (file_id, ordinal) identifies a node; children join on child.file_id = parent.file_id AND child.parent_ordinal = parent.ordinal.
+- kind is the go/ast type name including the pointer star, e.g. '*ast.FuncDecl', '*ast.CallExpr', '*ast.Ident', '*ast.ImportSpec'.
+- node_text is set for *ast.Ident (name), *ast.BasicLit (literal source), *ast.ImportSpec (quoted path), *ast.BinaryExpr and *ast.UnaryExpr (operator, e.g. '+', '!', '<-'), *ast.AssignStmt and *ast.IncDecStmt (token, e.g. ':=', '+=', '++'), *ast.BranchStmt (break, continue, goto, fallthrough), *ast.GenDecl (import, const, type, var), *ast.RangeStmt (':=' or '=', empty without a key), *ast.ChanType ('chan', 'chan<-' or '<-chan') and *ast.CallExpr ('...' when the last argument is spread); it is empty otherwise.
+- A node's descendants are the rows in the same file with start_offset >= parent.start_offset AND end_offset <= parent.end_offset.
+- The first *ast.Ident child of a *ast.FuncDecl or *ast.TypeSpec is its name.
+- "end" is a reserved word and must be quoted.
+- Use the node_source tool with (file_id, ordinal) to read the source text of a node.`
+
+type toolDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
+
+func objectSchema(required []string, props map[string]any) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func toolDefinitions() []toolDefinition {
+	maxRows := map[string]any{"type": "integer", "description": "maximum rows to return (capped by the server)"}
+	return []toolDefinition{
+		{
+			Name:        "query",
+			Description: "Run a single read-only DuckDB SELECT against the Go AST index; other statements are refused. Call describe_schema first if unsure about tables or node kinds.",
+			InputSchema: objectSchema([]string{"sql"}, map[string]any{
+				"sql":      map[string]any{"type": "string", "description": "DuckDB SQL query"},
+				"max_rows": maxRows,
+			}),
+		},
+		{
+			Name:        "list_helpers",
+			Description: "List the built-in explore helper queries with their IDs, descriptions, parameters and SQL.",
+			InputSchema: objectSchema(nil, map[string]any{}),
+		},
+		{
+			Name:        "run_helper",
+			Description: "Run one explore helper query by ID (see list_helpers).",
+			InputSchema: objectSchema([]string{"id"}, map[string]any{
+				"id":       map[string]any{"type": "string", "description": "helper ID, e.g. LARGE_FUNCTIONS_BY_LINES"},
+				"params":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}, "description": "helper parameters by name (see list_helpers), e.g. {\"min_lines\": \"80\"}"},
+				"max_rows": maxRows,
+			}),
+		},
+		{
+			Name:        "describe_schema",
+			Description: "Describe the AST index schema: tables, column types, the most common node kinds and query-writing hints.",
+			InputSchema: objectSchema(nil, map[string]any{}),
+		},
+		{
+			Name:        "run_rules",
+			Description: "Run enabled governance rules and return their violations.",
+			InputSchema: objectSchema(nil, map[string]any{
+				"rule_ids": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "restrict to these rule IDs"},
+				"max_rows": maxRows,
+			}),
+		},
+		{
+			Name:        "node_source",
+			Description: "Return the source code of an AST node by (file_id, ordinal), or of a line range by (path, start_line, end_line).",
+			InputSchema: objectSchema(nil, map[string]any{
+				"file_id":    map[string]any{"type": "integer"},
+				"ordinal":    map[string]any{"type": "integer"},
+				"path":       map[string]any{"type": "string", "description": "repo-relative file path"},
+				"start_line": map[string]any{"type": "integer"},
+				"end_line":   map[string]any{"type": "integer"},
+				"max_lines":  map[string]any{"type": "integer", "description": "maximum snippet lines to return"},
+			}),
+		},
+	}
+}
+
+func (s *Server) tools() map[string]toolHandler {
+	return map[string]toolHandler{
+		"query":           s.toolQuery,
+		"list_helpers":    s.toolListHelpers,
+		"run_helper":      s.toolRunHelper,
+		"describe_schema": s.toolDescribeSchema,
+		"run_rules":       s.toolRunRules,
+		"node_source":     s.toolNodeSource,
+	}
+}
+
+func decodeArgs(args json.RawMessage, dst any) error {
+	if err := json.Unmarshal(args, dst); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) toolQuery(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		SQL     string `json:"sql"`
+		MaxRows int    `json:"max_rows"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(in.SQL) == "" {
+		return nil, errors.New("sql is required")
+	}
+	t, err := s.selectTable(ctx, in.SQL)
+	if err != nil {
+		return nil, err
+	}
+	return s.boundTable(t, in.MaxRows), nil
+}
+
+func (s *Server) toolListHelpers(_ context.Context, _ json.RawMessage) (any, error) {
+	helpers, err := s.helpers()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		HelperQueries []explore.Query `json:"helper_queries"`
+	}{HelperQueries: helpers}, nil
+}
+
+func (s *Server) toolRunHelper(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		ID      string            `json:"id"`
+		Params  map[string]string `json:"params"`
+		MaxRows int               `json:"max_rows"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(in.ID) == "" {
+		return nil, errors.New("id is required")
+	}
+	helpers, err := s.helpers(strings.TrimSpace(in.ID))
+	if err != nil {
+		return nil, err
+	}
+	helper := helpers[0]
+	bindArgs, err := helper.Bind(in.Params)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.queryTable(ctx, helper.SQL, bindArgs...)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		Helper string      `json:"helper"`
+		Result tableOutput `json:"result"`
+	}{Helper: helper.ID, Result: s.boundTable(t, in.MaxRows)}, nil
+}
+
+func (s *Server) toolDescribeSchema(ctx context.Context, _ json.RawMessage) (any, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+	cols, err := s.runner.QueryTable(ctx, `
+SELECT table_name, column_name, data_type
+FROM information_schema.columns
+WHERE table_schema = 'main'
+ORDER BY table_name, ordinal_position`)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string][]string)
+	order := make([]string, 0)
+	for _, row := range cols.Rows {
+		name := fmt.Sprint(row[0])
+		if _, ok := tables[name]; !ok {
+			order = append(order, name)
+		}
+		tables[name] = append(tables[name], fmt.Sprintf("%v %v", row[1], row[2]))
+	}
+	type tableSchema struct {
+		Name    string   `json:"name"`
+		Columns []string `json:"columns"`
+	}
+	schema := make([]tableSchema, 0, len(order))
+	for _, name := range order {
+		schema = append(schema, tableSchema{Name: name, Columns: tables[name]})
+	}
+
+	kinds, err := s.runner.QueryTable(ctx, `SELECT kind, COUNT(*) AS n FROM nodes GROUP BY kind ORDER BY n DESC, kind LIMIT 60`)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		Tables      []tableSchema `json:"tables"`
+		CommonKinds tableOutput   `json:"common_kinds"`
+		Hints       string        `json:"hints"`
+	}{Tables: schema, CommonKinds: s.boundTable(kinds, 0), Hints: schemaHints}, nil
+}
+
+func (s *Server) toolRunRules(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		RuleIDs []string `json:"rule_ids"`
+		MaxRows int      `json:"max_rows"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+	report, err := s.runner.Check(ctx, governance.RunOptions{RuleIDs: in.RuleIDs})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]any, 0, len(report.Violations))
+	for _, v := range report.Violations {
+		rows = append(rows, []any{v.RuleID, v.Severity, v.Category, v.FilePath, v.Line, v.Symbol, v.Detail})
+	}
+	t := governance.Table{Columns: []string{"rule_id", "severity", "category", "file_path", "line", "symbol", "detail"}, Rows: rows}
+	out := s.boundTable(t, in.MaxRows)
+	// Failed rules do not abort the run; say which results are missing.
+	for _, f := range report.Failures() {
+		if out.Note != "" {
+			out.Note += "; "
+		}
+		out.Note += fmt.Sprintf("rule %s failed: %s", f.RuleID, f.Error)
+	}
+	return out, nil
+}
+
+func (s *Server) toolNodeSource(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		FileID    *int64 `json:"file_id"`
+		Ordinal   *int   `json:"ordinal"`
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		MaxLines  int    `json:"max_lines"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.MaxLines <= 0 || in.MaxLines > defaultMaxSnippet {
+		in.MaxLines = defaultMaxSnippet
+	}
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	type snippetOutput struct {
+		Path      string `json:"path"`
+		Kind      string `json:"kind,omitempty"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Source    string `json:"source"`
+		Truncated bool   `json:"truncated,omitempty"`
+	}
+
+	switch {
+	case in.FileID != nil && in.Ordinal != nil:
+		rows, err := s.runner.AdhocQuery(ctx, `
+SELECT f.path, n.kind, n.start_line, n.end_line, n.start_offset, n.end_offset
+FROM nodes n
+JOIN files f ON f.file_id = n.file_id
+WHERE n.file_id = ? AND n.ordinal = ?`, *in.FileID, *in.Ordinal)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("node (%d, %d) not found", *in.FileID, *in.Ordinal)
+		}
+		row := rows[0]
+		out := snippetOutput{
+			Path:      fmt.Sprint(row["path"]),
+			Kind:      fmt.Sprint(row["kind"]),
+			StartLine: toInt(row["start_line"]),
+			EndLine:   toInt(row["end_line"]),
+		}
+		src, truncated, err := readSnippet(s.cfg.RepoRoot, out.Path, toInt(row["start_offset"]), toInt(row["end_offset"]), in.MaxLines)
+		if err != nil {
+			return nil, err
+		}
+		out.Source, out.Truncated = src, truncated
+		return out, nil
+	case in.Path != "" && in.StartLine > 0:
+		if in.EndLine < in.StartLine {
+			in.EndLine = in.StartLine
+		}
+		src, truncated, err := readLines(s.cfg.RepoRoot, in.Path, in.StartLine, in.EndLine, in.MaxLines)
+		if err != nil {
+			return nil, err
+		}
+		return snippetOutput{Path: in.Path, StartLine: in.StartLine, EndLine: in.EndLine, Source: src, Truncated: truncated}, nil
+	default:
+		return nil, errors.New("provide either file_id and ordinal, or path and start_line")
+	}
+}
+
+func readLines(repoRoot, relPath string, startLine, endLine, maxLines int) (string, bool, error) {
+	all, _, err := readSnippet(repoRoot, relPath, 0, -1, 0)
+	if err != nil {
+		return "", false, err
+	}
+	lines := strings.SplitAfter(all, "\n")
+	if startLine > len(lines) {
+		return "", false, fmt.Errorf("start_line %d beyond end of %s (%d lines)", startLine, relPath, len(lines))
+	}
+	endLine = min(endLine, len(lines))
+	truncated := false
+	if endLine-startLine+1 > maxLines {
+		endLine = startLine + maxLines - 1
+		truncated = true
+	}
+	return strings.Join(lines[startLine-1:endLine], ""), truncated, nil
+}
+
+func toInt(v any) int {
+	switch x := v.(type) {
+	case int:
+		return x
+	case int32:
+		return int(x)
+	case int64:
+		return int(x)
+	case float64:
+		return int(x)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,417 @@
+package astdb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"math"
+	"sort"
+)
+
+// funcMetricsRow holds the metrics of one FuncDecl or FuncLit. Apart from
+// Lines and CodeLines, which cover the whole span, they leave out nested
+// function literals, which have rows of their own.
+type funcMetricsRow struct {
+	FileID     int64
+	Ordinal    int
+	Kind       string
+	Name       string
+	Cyclomatic int
+	Cognitive  int
+	MaxNesting int
+	Statements int
+	Params     int
+	Results    int
+	Returns    int
+	Lines      int
+	CodeLines  int
+	// Halstead counts: distinct and total operators and operands.
+	DistinctOperators int
+	DistinctOperands  int
+	Operators         int
+	Operands          int
+}
+
+// Volume, Difficulty and Effort are the derived Halstead measures.
+func (m funcMetricsRow) Volume() float64 {
+	n := m.DistinctOperators + m.DistinctOperands
+	if n == 0 {
+		return 0
+	}
+	return float64(m.Operators+m.Operands) * math.Log2(float64(n))
+}
+
+func (m funcMetricsRow) Difficulty() float64 {
+	if m.DistinctOperands == 0 {
+		return 0
+	}
+	return float64(m.DistinctOperators) / 2 * float64(m.Operands) / float64(m.DistinctOperands)
+}
+
+func (m funcMetricsRow) Effort() float64 { return m.Difficulty() * m.Volume() }
+
+// scannedToken is one token of a file; EndLine differs from Line for raw
+// strings spanning lines.
+type scannedToken struct {
+	Offset  int
+	Line    int
+	EndLine int
+	Tok     token.Token
+	Lit     string
+}
+
+// scanTokens lists the tokens of src without comments or automatically
+// inserted semicolons.
+func scanTokens(src []byte) []scannedToken {
+	fset := token.NewFileSet()
+	tf := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(tf, src, func(token.Position, string) {}, 0)
+	out := make([]scannedToken, 0, len(src)/4)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return out
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		line := tf.Line(pos)
+		t := scannedToken{Offset: tf.Offset(pos), Line: line, EndLine: line, Tok: tok, Lit: lit}
+		if tok == token.STRING {
+			t.EndLine = tf.Line(pos + token.Pos(len(lit)) - 1)
+		}
+		out = append(out, t)
+	}
+}
+
+// functionMetrics computes a row for every function in file. Ordinals follow
+// the same pre-order numbering as walkNodes.
+func functionMetrics(fset *token.FileSet, fileID int64, file *ast.File, src []byte) []funcMetricsRow {
+	ords := make(map[ast.Node]int)
+	ord := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n != nil {
+			ord++
+			switch n.(type) {
+			case *ast.FuncDecl, *ast.FuncLit:
+				ords[n] = ord
+			}
+		}
+		return true
+	})
+	if len(ords) == 0 {
+		return nil
+	}
+
+	mc := &metricsCollector{fset: fset, fileID: fileID, ords: ords, tokens: scanTokens(src)}
+	mc.codeLines = make(map[int]bool)
+	for _, t := range mc.tokens {
+		for l := t.Line; l <= t.EndLine; l++ {
+			mc.codeLines[l] = true
+		}
+	}
+	globals := 0
+	for _, d := range file.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			mc.add(fd, "*ast.FuncDecl", fd.Name.Name)
+			mc.literals(fd, fd.Name.Name, false, new(int))
+			continue
+		}
+		mc.literals(d, "glob.", false, &globals)
+	}
+	sort.Slice(mc.rows, func(i, j int) bool { return mc.rows[i].Ordinal < mc.rows[j].Ordinal })
+	return mc.rows
+}
+
+type metricsCollector struct {
+	fset      *token.FileSet
+	fileID    int64
+	ords      map[ast.Node]int
+	tokens    []scannedToken
+	codeLines map[int]bool
+	rows      []funcMetricsRow
+}
+
+// literals adds the function literals directly inside root, named after the
+// enclosing function as F.func1, F.func2 and, one level down, F.func1.1.
+func (mc *metricsCollector) literals(root ast.Node, outer string, inLiteral bool, counter *int) {
+	ast.Inspect(root, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok || n == root {
+			return true
+		}
+		*counter++
+		name := fmt.Sprintf("%s.func%d", outer, *counter)
+		if inLiteral {
+			name = fmt.Sprintf("%s.%d", outer, *counter)
+		}
+		mc.add(lit, "*ast.FuncLit", name)
+		mc.literals(lit, name, true, new(int))
+		return false
+	})
+}
+
+func (mc *metricsCollector) add(fn ast.Node, kind, name string) {
+	var (
+		typ  *ast.FuncType
+		body *ast.BlockStmt
+	)
+	self := selfCall{}
+	switch v := fn.(type) {
+	case *ast.FuncDecl:
+		typ, body = v.Type, v.Body
+		self.name = v.Name.Name
+		if v.Recv != nil && len(v.Recv.List) == 1 && len(v.Recv.List[0].Names) == 1 {
+			self.recv = v.Recv.List[0].Names[0].Name
+		}
+		self.method = v.Recv != nil
+	case *ast.FuncLit:
+		typ, body = v.Type, v.Body
+	}
+	sp := mc.fset.PositionFor(fn.Pos(), false)
+	ep := mc.fset.PositionFor(fn.End(), false)
+	row := funcMetricsRow{
+		FileID:     mc.fileID,
+		Ordinal:    mc.ords[fn],
+		Kind:       kind,
+		Name:       name,
+		Cyclomatic: 1,
+		Params:     fieldCount(typ.Params),
+		Results:    fieldCount(typ.Results),
+		Lines:      ep.Line - sp.Line + 1,
+	}
+	for l := sp.Line; l <= ep.Line; l++ {
+		if mc.codeLines[l] {
+			row.CodeLines++
+		}
+	}
+	if body != nil {
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch v := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+				row.Cyclomatic++
+			case *ast.CaseClause:
+				if v.List != nil {
+					row.Cyclomatic++
+				}
+			case *ast.CommClause:
+				if v.Comm != nil {
+					row.Cyclomatic++
+				}
+			case *ast.BinaryExpr:
+				if v.Op == token.LAND || v.Op == token.LOR {
+					row.Cyclomatic++
+				}
+			case *ast.ReturnStmt:
+				row.Returns++
+			}
+			switch n.(type) {
+			case nil, *ast.BlockStmt, *ast.CaseClause, *ast.CommClause, *ast.LabeledStmt, *ast.EmptyStmt:
+			case ast.Stmt:
+				row.Statements++
+			}
+			return true
+		})
+		cc := &cognitive{self: self}
+		cc.visit(body, 0)
+		row.Cognitive, row.MaxNesting = cc.score, cc.maxNesting
+	}
+	mc.halstead(fn, &row)
+	mc.rows = append(mc.rows, row)
+}
+
+// halstead counts identifiers and literals as operands and every other token
+// as an operator, a bracket pair counting once.
+func (mc *metricsCollector) halstead(fn ast.Node, row *funcMetricsRow) {
+	tf := mc.fset.File(fn.Pos())
+	if tf == nil {
+		return
+	}
+	start, end := tf.Offset(fn.Pos()), tf.Offset(fn.End())
+	var skip [][2]int
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && n != fn {
+			skip = append(skip, [2]int{tf.Offset(lit.Pos()), tf.Offset(lit.End())})
+			return false
+		}
+		return true
+	})
+	operators := make(map[string]bool)
+	operands := make(map[string]bool)
+	i := sort.Search(len(mc.tokens), func(i int) bool { return mc.tokens[i].Offset >= start })
+	for ; i < len(mc.tokens) && mc.tokens[i].Offset < end; i++ {
+		t := mc.tokens[i]
+		for len(skip) > 0 && t.Offset >= skip[0][1] {
+			skip = skip[1:]
+		}
+		if len(skip) > 0 && t.Offset >= skip[0][0] {
+			continue
+		}
+		switch t.Tok {
+		case token.IDENT, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.STRING:
+			row.Operands++
+			operands[t.Lit] = true
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+		default:
+			row.Operators++
+			operators[t.Tok.String()] = true
+		}
+	}
+	row.DistinctOperators, row.DistinctOperands = len(operators), len(operands)
+}
+
+func fieldCount(fl *ast.FieldList) int {
+	if fl == nil {
+		return 0
+	}
+	n := 0
+	for _, f := range fl.List {
+		n += max(len(f.Names), 1)
+	}
+	return n
+}
+
+// selfCall identifies recursive calls: name(...) for a function, or
+// recv.name(...) for a method.
+type selfCall struct {
+	name, recv string
+	method     bool
+}
+
+func (s selfCall) matches(call *ast.CallExpr) bool {
+	if s.name == "" {
+		return false
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return !s.method && fun.Name == s.name
+	case *ast.SelectorExpr:
+		x, ok := fun.X.(*ast.Ident)
+		return s.method && ok && s.recv != "" && x.Name == s.recv && fun.Sel.Name == s.name
+	}
+	return false
+}
+
+// cognitive computes cognitive complexity: each branch or loop adds one plus
+// its nesting, else and else-if add one, and so does each run of like
+// boolean operators, each goto or labeled branch and each recursive call.
+type cognitive struct {
+	self       selfCall
+	score      int
+	maxNesting int
+}
+
+func (c *cognitive) nested(n ast.Node, nesting int) {
+	c.maxNesting = max(c.maxNesting, nesting)
+	c.visit(n, nesting)
+}
+
+func (c *cognitive) visit(n ast.Node, nesting int) {
+	switch v := n.(type) {
+	case nil:
+		return
+	case *ast.FuncLit:
+		return
+	case *ast.IfStmt:
+		c.score += 1 + nesting
+		c.ifChain(v, nesting)
+		return
+	case *ast.ForStmt:
+		c.score += 1 + nesting
+		c.visit(v.Init, nesting)
+		c.visit(v.Cond, nesting)
+		c.visit(v.Post, nesting)
+		c.nested(v.Body, nesting+1)
+		return
+	case *ast.RangeStmt:
+		c.score += 1 + nesting
+		c.visit(v.X, nesting)
+		c.nested(v.Body, nesting+1)
+		return
+	case *ast.SwitchStmt:
+		c.score += 1 + nesting
+		c.visit(v.Init, nesting)
+		c.visit(v.Tag, nesting)
+		c.nested(v.Body, nesting+1)
+		return
+	case *ast.TypeSwitchStmt:
+		c.score += 1 + nesting
+		c.visit(v.Init, nesting)
+		c.visit(v.Assign, nesting)
+		c.nested(v.Body, nesting+1)
+		return
+	case *ast.SelectStmt:
+		c.score += 1 + nesting
+		c.nested(v.Body, nesting+1)
+		return
+	case *ast.BranchStmt:
+		if v.Label != nil {
+			c.score++
+		}
+		return
+	case *ast.BinaryExpr:
+		if v.Op == token.LAND || v.Op == token.LOR {
+			var ops []token.Token
+			var leaves []ast.Expr
+			flattenLogical(v, &ops, &leaves)
+			for i, op := range ops {
+				if i == 0 || op != ops[i-1] {
+					c.score++
+				}
+			}
+			for _, l := range leaves {
+				c.visit(l, nesting)
+			}
+			return
+		}
+	case *ast.CallExpr:
+		if c.self.matches(v) {
+			c.score++
+		}
+	}
+	for _, f := range NodeFields(n) {
+		for _, child := range f.Nodes {
+			c.visit(child, nesting)
+		}
+	}
+}
+
+// ifChain scores the else and else-if branches of an if statement, which
+// stay at the nesting of the first if.
+func (c *cognitive) ifChain(s *ast.IfStmt, nesting int) {
+	c.visit(s.Init, nesting)
+	c.visit(s.Cond, nesting)
+	c.nested(s.Body, nesting+1)
+	switch e := s.Else.(type) {
+	case *ast.IfStmt:
+		c.score++
+		c.ifChain(e, nesting)
+	case *ast.BlockStmt:
+		c.score++
+		c.nested(e, nesting+1)
+	}
+}
+
+// flattenLogical lists the && and || operators of a boolean expression in
+// source order, looking through parentheses, with the operands between them.
+func flattenLogical(e ast.Expr, ops *[]token.Token, leaves *[]ast.Expr) {
+	switch v := e.(type) {
+	case *ast.ParenExpr:
+		if b, ok := v.X.(*ast.BinaryExpr); ok && (b.Op == token.LAND || b.Op == token.LOR) {
+			flattenLogical(b, ops, leaves)
+			return
+		}
+	case *ast.BinaryExpr:
+		if v.Op == token.LAND || v.Op == token.LOR {
+			flattenLogical(v.X, ops, leaves)
+			*ops = append(*ops, v.Op)
+			flattenLogical(v.Y, ops, leaves)
+			return
+		}
+	}
+	*leaves = append(*leaves, e)
+}
@@ -0,0 +1,91 @@
+package astdb
+
+import (
+	"go/parser"
+	"go/token"
+	"math"
+	"testing"
+)
+
+func TestFunctionMetrics(t *testing.T) {
+	t.Parallel()
+
+	src := `package p
+
+func Add(a, b int) int { return a + b }
+
+// Sum adds.
+func Sum(xs []int, limit int) (total int, err error) {
+	for _, x := range xs {
+		if x > limit && limit > 0 || x < 0 {
+			return 0, nil
+		} else if x == 0 {
+			continue
+		} else {
+
+			// positive
+			total += x
+		}
+	}
+	f := func() int {
+		if total > 0 {
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case f() > 1:
+		return Sum(xs[1:], limit)
+	default:
+	}
+	return total, nil
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	rows := functionMetrics(fset, 1, file, []byte(src))
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rows)
+	}
+	byName := make(map[string]funcMetricsRow)
+	for _, r := range rows {
+		byName[r.Name] = r
+	}
+
+	add := byName["Add"]
+	if add.Kind != "*ast.FuncDecl" || add.Cyclomatic != 1 || add.Params != 2 || add.Results != 1 || add.Lines != 1 || add.CodeLines != 1 {
+		t.Fatalf("unexpected Add metrics: %+v", add)
+	}
+	// func ( , { return +  and  Add a b int int a b
+	if add.DistinctOperators != 6 || add.Operators != 6 || add.DistinctOperands != 4 || add.Operands != 7 {
+		t.Fatalf("unexpected Add Halstead counts: %+v", add)
+	}
+	if v := add.Volume(); math.Abs(v-13*math.Log2(10)) > 1e-9 {
+		t.Fatalf("Add volume = %v", v)
+	}
+	if d := add.Difficulty(); d != 5.25 {
+		t.Fatalf("Add difficulty = %v", d)
+	}
+
+	sum := byName["Sum"]
+	want := funcMetricsRow{
+		FileID: 1, Ordinal: sum.Ordinal, Kind: "*ast.FuncDecl", Name: "Sum",
+		Cyclomatic: 7, Cognitive: 9, MaxNesting: 2, Statements: 10,
+		Params: 2, Results: 2, Returns: 3, Lines: 25, CodeLines: 23,
+		DistinctOperators: sum.DistinctOperators, DistinctOperands: sum.DistinctOperands, Operators: sum.Operators, Operands: sum.Operands,
+	}
+	if sum != want {
+		t.Fatalf("Sum metrics:\n got %+v\nwant %+v", sum, want)
+	}
+
+	lit := byName["Sum.func1"]
+	if lit.Kind != "*ast.FuncLit" || lit.Cyclomatic != 2 || lit.Cognitive != 1 || lit.MaxNesting != 1 || lit.Returns != 2 || lit.Lines != 6 {
+		t.Fatalf("unexpected literal metrics: %+v", lit)
+	}
+	if !(add.Ordinal < sum.Ordinal && sum.Ordinal < lit.Ordinal) {
+		t.Fatalf("rows out of pre-order: %d %d %d", add.Ordinal, sum.Ordinal, lit.Ordinal)
+	}
+}
@@ -0,0 +1,227 @@
+package pattern
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+)
+
+type wildcard struct {
+	name string // "" for $_ and $*_
+	star bool
+}
+
+// asWildcard reports whether n stands for a wildcard. A wildcard alone in a
+// statement list or parameter list parses as an ExprStmt or an unnamed Field
+// and stands for the whole element.
+func asWildcard(n ast.Node) (wildcard, bool) {
+	switch v := n.(type) {
+	case *ast.Ident:
+		for _, prefix := range []string{starPrefix, singlePrefix} {
+			if name, ok := strings.CutPrefix(v.Name, prefix); ok {
+				if name == "_" {
+					name = ""
+				}
+				return wildcard{name: name, star: prefix == starPrefix}, true
+			}
+		}
+	case *ast.ExprStmt:
+		return asWildcard(v.X)
+	case *ast.Field:
+		if len(v.Names) == 0 && v.Tag == nil {
+			return asWildcard(v.Type)
+		}
+	}
+	return wildcard{}, false
+}
+
+// binding is where a named wildcard was first matched.
+type binding struct {
+	alias string
+	star  bool
+}
+
+// compiler turns a pattern tree into joins over nodes: one alias per pattern
+// node, each joined to its parent by field name and list index.
+type compiler struct {
+	n       int
+	joins   []string
+	where   []string
+	selects []string
+	binds   map[string]binding
+	vars    []string
+}
+
+func (c *compiler) alias() string {
+	a := fmt.Sprintf("n%d", c.n)
+	c.n++
+	return a
+}
+
+// child joins a new alias for the node in field of parent. index is a SQL
+// expression for list fields and empty otherwise.
+func (c *compiler) child(parent, field, index string) string {
+	a := c.alias()
+	cond := fmt.Sprintf("JOIN nodes %[1]s ON %[1]s.file_id = %[2]s.file_id AND %[1]s.parent_ordinal = %[2]s.ordinal AND %[1]s.field = %[3]s", a, parent, quote(field))
+	if index != "" {
+		cond += fmt.Sprintf(" AND %s.field_index = %s", a, index)
+	}
+	c.joins = append(c.joins, cond)
+	return a
+}
+
+func (c *compiler) node(n ast.Node, alias string) error {
+	if w, ok := asWildcard(n); ok {
+		if w.star {
+			return fmt.Errorf("$*%s must be an element of a list", displayName(w.name))
+		}
+		return c.bind(w.name, binding{alias: alias})
+	}
+	c.where = append(c.where,
+		fmt.Sprintf("%s.kind = %s", alias, quote(fmt.Sprintf("%T", n))),
+		fmt.Sprintf("coalesce(%s.node_text, '') = %s", alias, quote(astdb.NodeText(n))))
+	for _, f := range astdb.NodeFields(n) {
+		if astdb.IsCommentField(n, f) {
+			continue
+		}
+		if f.List {
+			if err := c.list(alias, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(f.Nodes) == 0 {
+			c.where = append(c.where, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM nodes c WHERE %s)", childOf(alias, f.Name)))
+			continue
+		}
+		if err := c.node(f.Nodes[0], c.child(alias, f.Name, "")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list matches a list field. Without a $* wildcard the lengths must agree;
+// with one, elements before it are matched from the start of the list and
+// elements after it from the end.
+func (c *compiler) list(alias string, f astdb.NodeField) error {
+	star := -1
+	for i, e := range f.Nodes {
+		if w, ok := asWildcard(e); ok && w.star {
+			if star >= 0 {
+				return fmt.Errorf("at most one $* wildcard per list (%s)", f.Name)
+			}
+			star = i
+		}
+	}
+	count := fmt.Sprintf("(SELECT count(*) FROM nodes c WHERE %s)", childOf(alias, f.Name))
+	if star < 0 {
+		c.where = append(c.where, fmt.Sprintf("%s = %d", count, len(f.Nodes)))
+		for i, e := range f.Nodes {
+			if err := c.node(e, c.child(alias, f.Name, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	after := len(f.Nodes) - star - 1
+	if star > 0 || after > 0 {
+		c.where = append(c.where, fmt.Sprintf("%s >= %d", count, len(f.Nodes)-1))
+	}
+	for i, e := range f.Nodes {
+		if i == star {
+			w, _ := asWildcard(e)
+			span := fmt.Sprintf("%s AND c.field_index >= %d AND c.field_index < %s - %d", childOf(alias, f.Name), star, count, after)
+			if err := c.bind(w.name, binding{alias: span, star: true}); err != nil {
+				return err
+			}
+			continue
+		}
+		index := fmt.Sprint(i)
+		if i > star {
+			index = fmt.Sprintf("%s - %d", count, len(f.Nodes)-i)
+		}
+		if err := c.node(e, c.child(alias, f.Name, index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bind records the first use of a named wildcard and requires later uses to
+// match the same code. A star binding's alias is the condition selecting the
+// list elements it covers.
+func (c *compiler) bind(name string, b binding) error {
+	if name == "" {
+		return nil
+	}
+	prev, seen := c.binds[name]
+	if !seen {
+		c.binds[name] = b
+		c.vars = append(c.vars, name)
+		if b.star {
+			c.selects = append(c.selects,
+				fmt.Sprintf("(SELECT min(c.start_offset) FROM nodes c WHERE %s) AS %s", b.alias, bindColumn(name, "start")),
+				fmt.Sprintf("(SELECT max(c.end_offset) FROM nodes c WHERE %s) AS %s", b.alias, bindColumn(name, "end")))
+		} else {
+			c.selects = append(c.selects,
+				fmt.Sprintf("%s.start_offset AS %s", b.alias, bindColumn(name, "start")),
+				fmt.Sprintf("%s.end_offset AS %s", b.alias, bindColumn(name, "end")))
+		}
+		return nil
+	}
+	if prev.star || b.star {
+		return fmt.Errorf("$*%s cannot be repeated", name)
+	}
+	c.where = append(c.where, sameTree(prev.alias, b.alias))
+	return nil
+}
+
+func (c *compiler) query() string {
+	var sb strings.Builder
+	sb.WriteString("SELECT n0.file_id, n0.ordinal, f.path AS file_path, coalesce(f.pkg_name, '') AS pkg_name, n0.start_line AS line, n0.start_col AS \"column\", n0.end_line, n0.end_col AS end_column, n0.start_offset, n0.end_offset")
+	for _, s := range c.selects {
+		sb.WriteString(",\n  " + s)
+	}
+	sb.WriteString("\nFROM nodes n0\nJOIN files f ON f.file_id = n0.file_id")
+	for _, j := range c.joins {
+		sb.WriteString("\n" + j)
+	}
+	if len(c.where) > 0 {
+		sb.WriteString("\nWHERE " + strings.Join(c.where, "\n  AND "))
+	}
+	sb.WriteString("\nORDER BY f.path, n0.ordinal")
+	return sb.String()
+}
+
+func childOf(alias, field string) string {
+	return fmt.Sprintf("c.file_id = %[1]s.file_id AND c.parent_ordinal = %[1]s.ordinal AND c.field = %[2]s", alias, quote(field))
+}
+
+// sameTree compares two subtrees node by node: kind, text, field and shape.
+// A node's descendants are the ordinals after it within its subtree_size.
+func sameTree(a, b string) string {
+	shape := func(x string) string {
+		return fmt.Sprintf(`(SELECT list(concat_ws(chr(31), d.kind, coalesce(d.node_text, ''), d.field, coalesce(d.field_index, -1), d.ordinal - %[1]s.ordinal, d.parent_ordinal - %[1]s.ordinal) ORDER BY d.ordinal)
+    FROM nodes d WHERE d.file_id = %[1]s.file_id AND d.ordinal > %[1]s.ordinal AND d.ordinal < %[1]s.ordinal + %[1]s.subtree_size)`, x)
+	}
+	return fmt.Sprintf("%[1]s.kind = %[2]s.kind AND coalesce(%[1]s.node_text, '') = coalesce(%[2]s.node_text, '')\n  AND %[3]s IS NOT DISTINCT FROM %[4]s", a, b, shape(a), shape(b))
+}
+
+func bindColumn(name, end string) string {
+	return fmt.Sprintf(`"bind_%s_%s"`, name, end)
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
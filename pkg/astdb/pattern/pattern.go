@@ -0,0 +1,196 @@
+// Package pattern compiles gogrep-style Go patterns into SQL over the nodes
+// table.
+//
+// A pattern is a Go expression, statement or declaration in which $name
+// matches any single node and $*name matches any number of list elements
+// (arguments, statements, fields, ...):
+//
+//	fmt.Errorf($format, $*args)
+//	if $err != nil { return $*_ }
+//	for $_, $v := range $xs { $*_ }
+//
+// A name used twice must match identical code each time; $_ and $*_ match
+// anything without binding. Everything else must match exactly, except
+// comments and formatting.
+package pattern
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Wildcards are rewritten to identifiers with these prefixes before parsing.
+const (
+	singlePrefix = "gogrep_w_"
+	starPrefix   = "gogrep_s_"
+)
+
+var wildcardRE = regexp.MustCompile(`\$(\*?)([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Pattern is a parsed, compiled pattern.
+type Pattern struct {
+	Source string
+	// Vars are the named wildcards in order of first use.
+	Vars []string
+	sql  string
+}
+
+// Match is one node matching a pattern. Bindings maps each named wildcard to
+// the source it matched; a $*name that matched nothing binds "".
+type Match struct {
+	FilePath    string            `json:"file_path"`
+	Package     string            `json:"package"`
+	Line        int               `json:"line"`
+	Column      int               `json:"column"`
+	EndLine     int               `json:"end_line"`
+	EndColumn   int               `json:"end_column"`
+	StartOffset int               `json:"start_offset"`
+	EndOffset   int               `json:"end_offset"`
+	Text        string            `json:"text"`
+	Bindings    map[string]string `json:"bindings,omitempty"`
+}
+
+// Parse parses and compiles src.
+func Parse(src string) (*Pattern, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("empty pattern")
+	}
+	node, err := parseGo(wildcardRE.ReplaceAllStringFunc(src, func(m string) string {
+		sub := wildcardRE.FindStringSubmatch(m)
+		if sub[1] == "*" {
+			return starPrefix + sub[2]
+		}
+		return singlePrefix + sub[2]
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("parse pattern: %s", strings.NewReplacer(starPrefix, "$*", singlePrefix, "$").Replace(err.Error()))
+	}
+	c := &compiler{binds: make(map[string]binding)}
+	if err := c.node(node, c.alias()); err != nil {
+		return nil, err
+	}
+	return &Pattern{Source: src, Vars: c.vars, sql: c.query()}, nil
+}
+
+// parseGo parses src as an expression, a single statement, or a single
+// declaration, in that order.
+func parseGo(src string) (ast.Node, error) {
+	expr, exprErr := parser.ParseExpr(src)
+	if exprErr == nil {
+		return expr, nil
+	}
+	if f, err := parser.ParseFile(token.NewFileSet(), "", "package p; func _() {\n"+src+"\n}", 0); err == nil {
+		body := f.Decls[0].(*ast.FuncDecl).Body.List
+		switch {
+		case len(body) > 1:
+			return nil, errors.New("a pattern must be a single expression, statement or declaration")
+		case len(body) == 1:
+			// A local declaration also matches the same declaration at top level.
+			if ds, ok := body[0].(*ast.DeclStmt); ok {
+				return ds.Decl, nil
+			}
+			return body[0], nil
+		}
+	}
+	if f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, 0); err == nil && len(f.Decls) == 1 {
+		return f.Decls[0], nil
+	}
+	return nil, exprErr
+}
+
+// SQL returns the compiled query. It yields one row per matching node with
+// file_id, ordinal, file_path, pkg_name, line, column, end_line, end_column,
+// start_offset and end_offset, plus bind_<name>_start and bind_<name>_end
+// offsets for each of Vars.
+func (p *Pattern) SQL() string { return p.sql }
+
+// Find runs the pattern against an index of repoRoot and reads the matched
+// source from disk, so files must not have changed since indexing.
+func (p *Pattern) Find(ctx context.Context, db *sql.DB, repoRoot string) ([]Match, error) {
+	rows, err := db.QueryContext(ctx, p.sql)
+	if err != nil {
+		return nil, fmt.Errorf("run pattern: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	sources := make(map[string][]byte)
+	out := make([]Match, 0)
+	for rows.Next() {
+		var (
+			m      Match
+			fileID int64
+			ord    int64
+			binds  = make([]sql.NullInt64, 2*len(p.Vars))
+		)
+		dest := []any{&fileID, &ord, &m.FilePath, &m.Package, &m.Line, &m.Column, &m.EndLine, &m.EndColumn, &m.StartOffset, &m.EndOffset}
+		for i := range binds {
+			dest = append(dest, &binds[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		src, ok := sources[m.FilePath]
+		if !ok {
+			src, err = os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(m.FilePath)))
+			if err != nil {
+				return nil, err
+			}
+			sources[m.FilePath] = src
+		}
+		text := func(start, end int) string {
+			if start < 0 || end > len(src) || start > end {
+				return ""
+			}
+			return string(src[start:end])
+		}
+		m.Text = text(m.StartOffset, m.EndOffset)
+		if len(p.Vars) > 0 {
+			m.Bindings = make(map[string]string, len(p.Vars))
+			for i, name := range p.Vars {
+				start, end := binds[2*i], binds[2*i+1]
+				m.Bindings[name] = ""
+				if start.Valid && end.Valid {
+					m.Bindings[name] = text(int(start.Int64), int(end.Int64))
+				}
+			}
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// CheckTemplate reports wildcards in a replacement template that the pattern
+// does not bind.
+func (p *Pattern) CheckTemplate(tmpl string) error {
+	for _, sub := range wildcardRE.FindAllStringSubmatch(tmpl, -1) {
+		name := sub[2]
+		if name == "_" {
+			return errors.New("$_ matches without binding and cannot be used in a replacement")
+		}
+		found := false
+		for _, v := range p.Vars {
+			found = found || v == name
+		}
+		if !found {
+			return fmt.Errorf("replacement uses $%s, which the pattern does not bind", name)
+		}
+	}
+	return nil
+}
+
+// Expand returns tmpl with each $name and $*name replaced by the source m
+// bound to it.
+func (p *Pattern) Expand(tmpl string, m Match) string {
+	return wildcardRE.ReplaceAllStringFunc(tmpl, func(s string) string {
+		return m.Bindings[wildcardRE.FindStringSubmatch(s)[2]]
+	})
+}
@@ -0,0 +1,137 @@
+package pattern
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yacobolo/goastdb/pkg/astdb"
+)
+
+const source = `package a
+
+import "fmt"
+
+var Limit = 10
+
+func F(a, b int) (int, error) {
+	if a == a {
+		fmt.Println(a, b, a+b)
+	}
+	if a.b == a.c {
+	}
+	x := 1
+	x = 2
+	fmt.Println()
+	fmt.Println(1, 2, 3, 4)
+	return x, fmt.Errorf("bad %d", a)
+}
+
+func G() {}
+
+func H(s []int) bool { return s[0]*2 == s[0]*2 || s[0]*2 == s[1]*2 }
+`
+
+func TestPattern_Find(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.go"), source)
+	ctx := context.Background()
+	db, err := astdb.BuildMemoryIndex(ctx, root)
+	if err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	cases := []struct {
+		pattern string
+		want    []string // "line: text [name=binding ...]"
+	}{
+		{`$x == $x`, []string{"8: a == a [x=a]", "22: s[0]*2 == s[0]*2 [x=s[0]*2]"}},
+		{`fmt.Println($first, $*rest)`, []string{"9: fmt.Println(a, b, a+b) [first=a rest=b, a+b]", "16: fmt.Println(1, 2, 3, 4) [first=1 rest=2, 3, 4]"}},
+		{`fmt.Println($*_, 4)`, []string{"16: fmt.Println(1, 2, 3, 4) []"}},
+		{`fmt.Println($*args)`, []string{"9: fmt.Println(a, b, a+b) [args=a, b, a+b]", "15: fmt.Println() [args=]", "16: fmt.Println(1, 2, 3, 4) [args=1, 2, 3, 4]"}},
+		{`$x := $v`, []string{"13: x := 1 [x=x v=1]"}},
+		{`$a + $b`, []string{"9: a+b [a=a b=b]"}},
+		{`$a - $b`, nil},
+		{`func $name() { $*_ }`, []string{"20: func G() {} [name=G]"}},
+		{`var $name = $value`, []string{"5: var Limit = 10 [name=Limit value=10]"}},
+		{`if $c { $*body }`, []string{"8: if a == a {\n\t\tfmt.Println(a, b, a+b)\n\t} [c=a == a body=fmt.Println(a, b, a+b)]", "11: if a.b == a.c {\n\t} [c=a.b == a.c body=]"}},
+	}
+	for _, tc := range cases {
+		p, err := Parse(tc.pattern)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.pattern, err)
+		}
+		matches, err := p.Find(ctx, db, root)
+		if err != nil {
+			t.Fatalf("find %q: %v", tc.pattern, err)
+		}
+		got := make([]string, 0, len(matches))
+		for _, m := range matches {
+			binds := make([]string, 0, len(p.Vars))
+			for _, name := range p.Vars {
+				binds = append(binds, name+"="+m.Bindings[name])
+			}
+			got = append(got, fmt.Sprintf("%d: %s [%s]", m.Line, m.Text, strings.Join(binds, " ")))
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s:\ngot  %q\nwant %q", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		``:                      "empty pattern",
+		`a b`:                   "parse pattern",
+		`x := 1; x = 2`:         "single expression, statement or declaration",
+		`$*xs`:                  "must be an element of a list",
+		`f($*a, $*b)`:           "at most one $* wildcard",
+		`f($*a); g($*a)`:        "single expression",
+		`if $c { f($*a, $*a) }`: "at most one $* wildcard",
+		`f($*a) + g($*a)`:       "cannot be repeated",
+	}
+	for src, want := range cases {
+		if _, err := Parse(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", src, err, want)
+		}
+	}
+}
+
+func TestPattern_Expand(t *testing.T) {
+	t.Parallel()
+
+	p, err := Parse(`fmt.Println($x, $*rest)`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	m := Match{Bindings: map[string]string{"x": "a", "rest": "b, c"}}
+	if got, want := p.Expand(`log.Print($rest, $x, $x)`, m), "log.Print(b, c, a, a)"; got != want {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+	if err := p.CheckTemplate(`f($*rest)`); err != nil {
+		t.Errorf("CheckTemplate: %v", err)
+	}
+	for tmpl, want := range map[string]string{`f($y)`: "does not bind", `f($_)`: "without binding"} {
+		if err := p.CheckTemplate(tmpl); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckTemplate(%q) = %v, want error containing %q", tmpl, err, want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
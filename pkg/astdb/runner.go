@@ -2,14 +2,17 @@ package astdb
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"hash/fnv"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -22,7 +25,7 @@ import (
 	duckdb "github.com/duckdb/duckdb-go/v2"
 )
 
-const schemaVersion = "1"
+const schemaVersion = "8"
 
 type Options struct {
 	RepoRoot        string
@@ -73,6 +76,10 @@ type SyncStats struct {
 	ParseErrors  int
 	FilesCount   int64
 	NodesCount   int64
+	// RolledBack is set when a rebuild failed and the previous database was
+	// kept; RollbackError says why.
+	RolledBack    bool
+	RollbackError string
 }
 
 type QueryResult struct {
@@ -92,6 +99,9 @@ type fileRow struct {
 	PkgName    string
 	ParseError string
 	Bytes      int64
+	// ContentHash is the hex SHA-256 of the file as parsed, so edits based
+	// on the index can check the file has not changed since.
+	ContentHash string
 }
 
 type nodeRow struct {
@@ -109,6 +119,29 @@ type nodeRow struct {
 	EndCol        int
 	StartOffset   int
 	EndOffset     int
+	// Field names the parent field holding the node (Fun, Args, ...);
+	// FieldIndex is its position in a list field, or -1.
+	Field      string
+	FieldIndex int
+	// SubtreeSize counts the node and its descendants, so the subtree spans
+	// ordinals [Ordinal, Ordinal+SubtreeSize). SubtreeHash is set for
+	// statements and functions; see subtreeHasher.
+	SubtreeSize int
+	SubtreeHash uint64
+	HasHash     bool
+}
+
+// commentRow is one // or /* */ comment. Comments in the same ast.CommentGroup
+// share a GroupOrdinal.
+type commentRow struct {
+	FileID       int64
+	Ordinal      int
+	GroupOrdinal int
+	Text         string
+	StartLine    int
+	StartCol     int
+	EndLine      int
+	EndCol       int
 }
 
 type dbState struct {
@@ -120,8 +153,10 @@ type dbState struct {
 }
 
 type parseResult struct {
-	File fileRow
-	Rows []nodeRow
+	File     fileRow
+	Rows     []nodeRow
+	Comments []commentRow
+	Metrics  []funcMetricsRow
 }
 
 func Run(ctx context.Context, opts Options) (Result, error) {
@@ -207,29 +242,44 @@ func Run(ctx context.Context, opts Options) (Result, error) {
 	} else {
 		action = "rebuild"
 		parseStart := time.Now()
-		files, nodes, parseErrors := parseFiles(repoRoot, metas, opts.Workers)
+		files, nodes, comments, metrics, parseErrors := parseFiles(repoRoot, metas, opts.Workers)
 		parseElapsed := time.Since(parseStart)
 
 		loadStart := time.Now()
-		if err := writeDatabase(ctx, dbPath, files, nodes, fingerprint); err != nil {
-			return Result{}, err
-		}
+		writeErr := writeDatabase(ctx, dbPath, files, nodes, comments, metrics, fingerprint)
 		loadElapsed := time.Since(loadStart)
+		if writeErr != nil {
+			// The live database is untouched; keep serving it if it is usable.
+			if !state.Exists || state.SchemaVersion != schemaVersion {
+				return Result{}, writeErr
+			}
+			res.Sync = SyncStats{
+				Action:        "rollback",
+				Reason:        reason,
+				ParseErrors:   parseErrors,
+				ParseElapsed:  parseElapsed,
+				LoadElapsed:   loadElapsed,
+				FilesCount:    state.FilesCount,
+				NodesCount:    state.NodesCount,
+				RolledBack:    true,
+				RollbackError: writeErr.Error(),
+			}
+		} else {
+			counts, err := inspectDuckDB(dbPath)
+			if err != nil {
+				return Result{}, err
+			}
 
-		counts, err := inspectDuckDB(dbPath)
-		if err != nil {
-			return Result{}, err
-		}
-
-		res.Sync = SyncStats{
-			Action:       action,
-			Reason:       reason,
-			Changed:      len(metas),
-			ParseErrors:  parseErrors,
-			ParseElapsed: parseElapsed,
-			LoadElapsed:  loadElapsed,
-			FilesCount:   counts.FilesCount,
-			NodesCount:   counts.NodesCount,
+			res.Sync = SyncStats{
+				Action:       action,
+				Reason:       reason,
+				Changed:      len(metas),
+				ParseErrors:  parseErrors,
+				ParseElapsed: parseElapsed,
+				LoadElapsed:  loadElapsed,
+				FilesCount:   counts.FilesCount,
+				NodesCount:   counts.NodesCount,
+			}
 		}
 	}
 
@@ -288,7 +338,7 @@ func normalizeAndValidateOptions(opts *Options) error {
 }
 
 func collectGoFiles(repoRoot, subdir string, maxFiles int) ([]fileMeta, error) {
-	skipDirs := map[string]struct{}{".git": {}, "vendor": {}, "node_modules": {}, "bin": {}, ".tmp": {}, "tmp": {}, ".cache": {}}
+	skipDirs := map[string]struct{}{".git": {}, ".goast": {}, "vendor": {}, "node_modules": {}, "bin": {}, ".tmp": {}, "tmp": {}, ".cache": {}, "testdata": {}}
 	root := repoRoot
 	if subdir != "" {
 		root = filepath.Join(repoRoot, subdir)
@@ -300,7 +350,9 @@ func collectGoFiles(repoRoot, subdir string, maxFiles int) ([]fileMeta, error) {
 			return walkErr
 		}
 		if d.IsDir() {
-			if _, ok := skipDirs[d.Name()]; ok {
+			// The root itself is never skipped, so a fixture under testdata/
+			// can still be indexed on its own.
+			if _, ok := skipDirs[d.Name()]; ok && path != root {
 				return filepath.SkipDir
 			}
 			return nil
@@ -329,7 +381,7 @@ func collectGoFiles(repoRoot, subdir string, maxFiles int) ([]fileMeta, error) {
 	return files, nil
 }
 
-func parseFiles(repoRoot string, metas []fileMeta, workers int) ([]fileRow, []nodeRow, int) {
+func parseFiles(repoRoot string, metas []fileMeta, workers int) ([]fileRow, []nodeRow, []commentRow, []funcMetricsRow, int) {
 	jobs := make(chan fileMeta)
 	out := make(chan parseResult, len(metas))
 	var wg sync.WaitGroup
@@ -355,6 +407,8 @@ func parseFiles(repoRoot string, metas []fileMeta, workers int) ([]fileRow, []no
 
 	files := make([]fileRow, 0, len(metas))
 	nodes := make([]nodeRow, 0, len(metas)*256)
+	comments := make([]commentRow, 0, len(metas)*16)
+	metrics := make([]funcMetricsRow, 0, len(metas)*8)
 	parseErrors := 0
 	for r := range out {
 		if r.File.ParseError != "" {
@@ -362,6 +416,8 @@ func parseFiles(repoRoot string, metas []fileMeta, workers int) ([]fileRow, []no
 		}
 		files = append(files, r.File)
 		nodes = append(nodes, r.Rows...)
+		comments = append(comments, r.Comments...)
+		metrics = append(metrics, r.Metrics...)
 	}
 
 	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
@@ -371,8 +427,20 @@ func parseFiles(repoRoot string, metas []fileMeta, workers int) ([]fileRow, []no
 		}
 		return nodes[i].FileID < nodes[j].FileID
 	})
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].FileID == comments[j].FileID {
+			return comments[i].Ordinal < comments[j].Ordinal
+		}
+		return comments[i].FileID < comments[j].FileID
+	})
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].FileID == metrics[j].FileID {
+			return metrics[i].Ordinal < metrics[j].Ordinal
+		}
+		return metrics[i].FileID < metrics[j].FileID
+	})
 
-	return files, nodes, parseErrors
+	return files, nodes, comments, metrics, parseErrors
 }
 
 func parseFile(repoRoot string, meta fileMeta) parseResult {
@@ -384,7 +452,8 @@ func parseFile(repoRoot string, meta fileMeta) parseResult {
 	}
 	fset := token.NewFileSet()
 	parsed, parseErr := parser.ParseFile(fset, abs, b, parser.ParseComments|parser.AllErrors)
-	row := fileRow{ID: fileID, Path: meta.RelPath, Bytes: int64(len(b))}
+	sum := sha256.Sum256(b)
+	row := fileRow{ID: fileID, Path: meta.RelPath, Bytes: int64(len(b)), ContentHash: hex.EncodeToString(sum[:])}
 	if parseErr != nil {
 		row.ParseError = parseErr.Error()
 	}
@@ -394,26 +463,67 @@ func parseFile(repoRoot string, meta fileMeta) parseResult {
 	if parsed == nil {
 		return parseResult{File: row}
 	}
-	return parseResult{File: row, Rows: walkNodes(fset, fileID, parsed)}
+	return parseResult{
+		File:     row,
+		Rows:     walkNodes(fset, fileID, parsed),
+		Comments: collectComments(fset, fileID, parsed),
+		Metrics:  functionMetrics(fset, fileID, parsed, b),
+	}
+}
+
+// collectComments lists every comment in file, including the free-floating
+// ones ast.Inspect never visits.
+func collectComments(fset *token.FileSet, fileID int64, file *ast.File) []commentRow {
+	rows := make([]commentRow, 0)
+	for gi, group := range file.Comments {
+		for _, c := range group.List {
+			sp := fset.PositionFor(c.Pos(), false)
+			ep := fset.PositionFor(c.End(), false)
+			rows = append(rows, commentRow{
+				FileID:       fileID,
+				Ordinal:      len(rows) + 1,
+				GroupOrdinal: gi + 1,
+				Text:         c.Text,
+				StartLine:    sp.Line,
+				StartCol:     sp.Column,
+				EndLine:      ep.Line,
+				EndCol:       ep.Column,
+			})
+		}
+	}
+	return rows
 }
 
 func walkNodes(fset *token.FileSet, fileID int64, file *ast.File) []nodeRow {
 	rows := make([]nodeRow, 0, 1024)
 	stack := make([]int, 0, 256)
+	refs := make([]map[ast.Node]fieldRef, 0, 256)
+	hashes := make([]subtreeHasher, 0, 256)
 	ord := 0
 	ast.Inspect(file, func(n ast.Node) bool {
 		if n == nil {
 			if len(stack) > 0 {
+				row := &rows[stack[len(stack)-1]-1]
+				h := &hashes[len(hashes)-1]
+				row.SubtreeSize = ord - row.Ordinal + 1
+				row.SubtreeHash, row.HasHash = h.sum()
+				if len(hashes) > 1 && !h.comment {
+					hashes[len(hashes)-2].child(row.Field, row.SubtreeHash)
+				}
 				stack = stack[:len(stack)-1]
+				refs = refs[:len(refs)-1]
+				hashes = hashes[:len(hashes)-1]
 			}
 			return true
 		}
 		ord++
 		parentOrd := 0
 		hasParent := false
+		ref := fieldRef{Index: -1}
 		if len(stack) > 0 {
 			parentOrd = stack[len(stack)-1]
 			hasParent = true
+			ref = refs[len(refs)-1][n]
 		}
 		sp := fset.PositionFor(n.Pos(), false)
 		ep := fset.PositionFor(n.End(), false)
@@ -428,7 +538,7 @@ func walkNodes(fset *token.FileSet, fileID int64, file *ast.File) []nodeRow {
 			ParentOrdinal: parentOrd,
 			HasParent:     hasParent,
 			Kind:          fmt.Sprintf("%T", n),
-			NodeText:      extractNodeText(n),
+			NodeText:      NodeText(n),
 			Pos:           int(n.Pos()),
 			End:           int(n.End()),
 			StartLine:     sp.Line,
@@ -437,34 +547,135 @@ func walkNodes(fset *token.FileSet, fileID int64, file *ast.File) []nodeRow {
 			EndCol:        ep.Column,
 			StartOffset:   so,
 			EndOffset:     eo,
+			Field:         ref.Name,
+			FieldIndex:    ref.Index,
 		})
 		stack = append(stack, ord)
+		refs = append(refs, childFieldRefs(n))
+		hashes = append(hashes, newSubtreeHasher(n, rows[len(rows)-1].Kind))
 		return true
 	})
 	return rows
 }
 
-func extractNodeText(n ast.Node) string {
-	switch v := n.(type) {
-	case *ast.Ident:
-		return v.Name
-	case *ast.BasicLit:
-		return v.Value
-	case *ast.ImportSpec:
-		if v.Path != nil {
-			return v.Path.Value
-		}
+// ownedTables are the index tables a rebuild drops and recreates. Every other
+// table in the database, including governance_rules, belongs to users and
+// survives rebuilds.
+var ownedTables = []string{"files", "nodes", "comments", "function_metrics", "run_meta"}
+
+// writeDatabase rebuilds the index in a shadow copy of the live database,
+// validates it and renames it over the live file. Readers keep the old file
+// until the swap, and a failed rebuild leaves it untouched.
+func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []nodeRow, comments []commentRow, metrics []funcMetricsRow, fingerprint string) error {
+	shadow := path + ".rebuild"
+	cleanupDuckDB(shadow)
+	fail := func(err error) error {
+		cleanupDuckDB(shadow)
+		return err
+	}
+	if err := copyDatabase(ctx, path, shadow); err != nil {
+		return fail(err)
+	}
+	if err := loadIndex(ctx, shadow, files, nodes, comments, metrics, fingerprint); err != nil {
+		return fail(err)
+	}
+	if err := validateIndex(shadow, len(files), len(nodes)); err != nil {
+		return fail(err)
+	}
+	// The live file was checkpointed by copyDatabase; a leftover WAL would be
+	// replayed against the new file.
+	_ = os.Remove(path + ".wal")
+	if err := os.Rename(shadow, path); err != nil {
+		return fail(fmt.Errorf("swap database: %w", err))
+	}
+	return nil
+}
+
+// copyDatabase checkpoints the live database, if any, and copies it to dst so
+// user tables carry over into the rebuilt index.
+func copyDatabase(ctx context.Context, src, dst string) error {
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	db, err := sql.Open("duckdb", src)
+	if err != nil {
+		return fmt.Errorf("open live database: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `CHECKPOINT`)
+	_ = db.Close()
+	if err != nil {
+		return fmt.Errorf("checkpoint live database (remove %s to rebuild from scratch): %w", src, err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("copy live database: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("copy live database: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copy live database: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("copy live database: %w", err)
+	}
+	return nil
+}
+
+// validateIndex checks a freshly written index before it replaces the live one.
+func validateIndex(path string, files, nodes int) error {
+	state, err := inspectDuckDB(path)
+	if err != nil {
+		return err
+	}
+	if state.SchemaVersion != schemaVersion {
+		return fmt.Errorf("validate rebuild: schema_version %q, want %q", state.SchemaVersion, schemaVersion)
+	}
+	if state.FilesCount != int64(files) || state.NodesCount != int64(nodes) {
+		return fmt.Errorf("validate rebuild: got %d files and %d nodes, want %d and %d", state.FilesCount, state.NodesCount, files, nodes)
 	}
-	return ""
+	return nil
 }
 
-func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []nodeRow, fingerprint string) error {
-	cleanupDuckDB(path)
+func loadIndex(ctx context.Context, path string, files []fileRow, nodes []nodeRow, comments []commentRow, metrics []funcMetricsRow, fingerprint string) error {
 	db, err := sql.Open("duckdb", path)
 	if err != nil {
 		return fmt.Errorf("open duckdb: %w", err)
 	}
 	defer func() { _ = db.Close() }()
+	return loadIndexInto(ctx, db, files, nodes, comments, metrics, fingerprint)
+}
+
+// BuildMemoryIndex parses every Go file under dir into an in-memory database
+// with the same tables and views as an on-disk index; paths are relative to
+// dir. It suits small fixture trees such as rule tests. The caller closes the
+// database.
+func BuildMemoryIndex(ctx context.Context, dir string) (*sql.DB, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve fixture dir: %w", err)
+	}
+	metas, err := collectGoFiles(root, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	files, nodes, comments, metrics, _ := parseFiles(root, metas, runtime.NumCPU())
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	if err := loadIndexInto(ctx, db, files, nodes, comments, metrics, sourceFingerprint(metas)); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func loadIndexInto(ctx context.Context, db *sql.DB, files []fileRow, nodes []nodeRow, comments []commentRow, metrics []funcMetricsRow, fingerprint string) error {
 	conn, err := db.Conn(ctx)
 	if err != nil {
 		return fmt.Errorf("open conn: %w", err)
@@ -475,10 +686,6 @@ func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []no
 		return fmt.Errorf("set threads: %w", err)
 	}
 
-	if err := createSchema(ctx, conn); err != nil {
-		return err
-	}
-
 	if _, err := conn.ExecContext(ctx, `BEGIN TRANSACTION`); err != nil {
 		return err
 	}
@@ -486,6 +693,14 @@ func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []no
 		_, _ = conn.ExecContext(ctx, `ROLLBACK`)
 		return e
 	}
+	for _, table := range ownedTables {
+		if _, err := conn.ExecContext(ctx, `DROP TABLE IF EXISTS `+table); err != nil {
+			return rollback(fmt.Errorf("drop %s: %w", table, err))
+		}
+	}
+	if err := createSchema(ctx, conn); err != nil {
+		return rollback(err)
+	}
 
 	err = conn.Raw(func(raw any) error {
 		rawConn, ok := raw.(driver.Conn)
@@ -502,13 +717,23 @@ func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []no
 			return err
 		}
 		defer func() { _ = na.Close() }()
+		ca, err := duckdb.NewAppenderFromConn(rawConn, "", "comments")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = ca.Close() }()
+		ma, err := duckdb.NewAppenderFromConn(rawConn, "", "function_metrics")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = ma.Close() }()
 
 		for _, f := range files {
 			var pe any
 			if f.ParseError != "" {
 				pe = f.ParseError
 			}
-			if err := fa.AppendRow(f.ID, f.Path, f.PkgName, pe, f.Bytes); err != nil {
+			if err := fa.AppendRow(f.ID, f.Path, f.PkgName, pe, f.Bytes, f.ContentHash); err != nil {
 				return err
 			}
 		}
@@ -517,7 +742,29 @@ func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []no
 			if n.HasParent {
 				parent = n.ParentOrdinal
 			}
-			if err := na.AppendRow(n.FileID, n.Ordinal, parent, n.Kind, n.NodeText, n.Pos, n.End, n.StartLine, n.StartCol, n.EndLine, n.EndCol, n.StartOffset, n.EndOffset); err != nil {
+			var field, index any
+			if n.HasParent {
+				field = n.Field
+			}
+			if n.FieldIndex >= 0 {
+				index = n.FieldIndex
+			}
+			var hash any
+			if n.HasHash {
+				hash = n.SubtreeHash
+			}
+			if err := na.AppendRow(n.FileID, n.Ordinal, parent, n.Kind, n.NodeText, n.Pos, n.End, n.StartLine, n.StartCol, n.EndLine, n.EndCol, n.StartOffset, n.EndOffset, field, index, n.SubtreeSize, hash); err != nil {
+				return err
+			}
+		}
+		for _, c := range comments {
+			if err := ca.AppendRow(c.FileID, c.Ordinal, c.GroupOrdinal, c.Text, c.StartLine, c.StartCol, c.EndLine, c.EndCol); err != nil {
+				return err
+			}
+		}
+		for _, m := range metrics {
+			if err := ma.AppendRow(m.FileID, m.Ordinal, m.Kind, m.Name, m.Cyclomatic, m.Cognitive, m.MaxNesting, m.Statements, m.Params, m.Results, m.Returns, m.Lines, m.CodeLines,
+				m.DistinctOperators, m.DistinctOperands, m.Operators, m.Operands, m.Volume(), m.Difficulty(), m.Effort()); err != nil {
 				return err
 			}
 		}
@@ -538,16 +785,36 @@ func writeDatabase(ctx context.Context, path string, files []fileRow, nodes []no
 
 func createSchema(ctx context.Context, conn *sql.Conn) error {
 	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS files (file_id BIGINT PRIMARY KEY, path TEXT NOT NULL UNIQUE, pkg_name TEXT, parse_error TEXT, bytes BIGINT)`,
-		`CREATE TABLE IF NOT EXISTS nodes (file_id BIGINT NOT NULL, ordinal INTEGER NOT NULL, parent_ordinal INTEGER, kind TEXT NOT NULL, node_text TEXT, pos INTEGER, "end" INTEGER, start_line INTEGER, start_col INTEGER, end_line INTEGER, end_col INTEGER, start_offset INTEGER, end_offset INTEGER, PRIMARY KEY(file_id, ordinal))`,
+		`CREATE TABLE IF NOT EXISTS files (file_id BIGINT PRIMARY KEY, path TEXT NOT NULL UNIQUE, pkg_name TEXT, parse_error TEXT, bytes BIGINT, content_hash TEXT)`,
+		`CREATE TABLE IF NOT EXISTS nodes (file_id BIGINT NOT NULL, ordinal INTEGER NOT NULL, parent_ordinal INTEGER, kind TEXT NOT NULL, node_text TEXT, pos INTEGER, "end" INTEGER, start_line INTEGER, start_col INTEGER, end_line INTEGER, end_col INTEGER, start_offset INTEGER, end_offset INTEGER, field TEXT, field_index INTEGER, subtree_size INTEGER, subtree_hash UBIGINT, PRIMARY KEY(file_id, ordinal))`,
+		`CREATE TABLE IF NOT EXISTS comments (file_id BIGINT NOT NULL, ordinal INTEGER NOT NULL, group_ordinal INTEGER NOT NULL, text TEXT NOT NULL, start_line INTEGER, start_col INTEGER, end_line INTEGER, end_col INTEGER, PRIMARY KEY(file_id, ordinal))`,
+		`CREATE TABLE IF NOT EXISTS function_metrics (file_id BIGINT NOT NULL, ordinal INTEGER NOT NULL, kind TEXT NOT NULL, name TEXT NOT NULL, cyclomatic INTEGER NOT NULL, cognitive INTEGER NOT NULL, max_nesting INTEGER NOT NULL, statements INTEGER NOT NULL, params INTEGER NOT NULL, results INTEGER NOT NULL, returns INTEGER NOT NULL, loc INTEGER NOT NULL, sloc INTEGER NOT NULL, halstead_distinct_operators INTEGER NOT NULL, halstead_distinct_operands INTEGER NOT NULL, halstead_operators INTEGER NOT NULL, halstead_operands INTEGER NOT NULL, halstead_volume DOUBLE NOT NULL, halstead_difficulty DOUBLE NOT NULL, halstead_effort DOUBLE NOT NULL, PRIMARY KEY(file_id, ordinal))`,
 		`CREATE TABLE IF NOT EXISTS run_meta (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
-		`CREATE TABLE IF NOT EXISTS governance_rules (rule_id TEXT PRIMARY KEY, category TEXT NOT NULL, severity TEXT NOT NULL, description TEXT NOT NULL, query_sql TEXT NOT NULL, enabled BOOLEAN NOT NULL DEFAULT true, updated_unix BIGINT NOT NULL)`,
 	}
-	for _, stmt := range stmts {
+	for _, stmt := range append(stmts, rulesTableStatements...) {
 		if _, err := conn.ExecContext(ctx, stmt); err != nil {
 			return err
 		}
 	}
+	return createViews(ctx, conn)
+}
+
+// rulesTableStatements define governance_rules. The table outlives rebuilds,
+// so columns added by later schema versions are migrated in place.
+var rulesTableStatements = []string{
+	`CREATE TABLE IF NOT EXISTS governance_rules (rule_id TEXT PRIMARY KEY, category TEXT NOT NULL, severity TEXT NOT NULL, description TEXT NOT NULL, query_sql TEXT NOT NULL, enabled BOOLEAN NOT NULL DEFAULT true, updated_unix BIGINT NOT NULL, source TEXT NOT NULL DEFAULT '', pattern TEXT DEFAULT '')`,
+	`ALTER TABLE governance_rules ADD COLUMN IF NOT EXISTS source TEXT DEFAULT ''`,
+	`ALTER TABLE governance_rules ADD COLUMN IF NOT EXISTS pattern TEXT DEFAULT ''`,
+}
+
+// EnsureRulesTable creates governance_rules in db, or migrates an existing
+// one to the current columns.
+func EnsureRulesTable(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range rulesTableStatements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("ensure governance_rules table: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -2,6 +2,9 @@ package astdb
 
 import (
 	"context"
+	"database/sql"
+	"go/parser"
+	"go/token"
 	"os"
 	"path/filepath"
 	"testing"
@@ -65,3 +68,218 @@ func writeGoFile(t *testing.T, path, content string) {
 		t.Fatalf("write file: %v", err)
 	}
 }
+
+func TestRun_ViewsAndMacros(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeGoFile(t, filepath.Join(root, "main.go"), `package main
+
+import (
+	"fmt"
+	str "strings"
+
+	"example.com/dep"
+)
+
+type Server struct {
+	Name string
+	Port int
+}
+
+type Store interface {
+	Get(key string) string
+}
+
+func (s *Server) Start(args []string) {
+	fmt.Println(str.ToUpper(s.Name), dep.X)
+}
+
+func main() {
+	panic("boom")
+}
+`)
+
+	opts := DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := Run(context.Background(), opts); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT string_agg(name || ':' || coalesce(receiver, '-'), ',' ORDER BY name) FROM func_decls`, "Start:Server,main:-"},
+		{`SELECT string_agg(name, ',') FROM methods`, "Start"},
+		{`SELECT string_agg(name, ',') FROM functions`, "main"},
+		{`SELECT name || ':' || field_count FROM struct_types`, "Server:2"},
+		{`SELECT name || ':' || method_count FROM interfaces`, "Store:1"},
+		{`SELECT string_agg(callee || '/' || arg_count, ',' ORDER BY start_offset) FROM call_sites`, "fmt.Println/2,str.ToUpper/1,panic/1"},
+		{`SELECT func_name(file_id, ordinal) FROM call_sites WHERE callee = 'panic'`, "main"},
+		{`SELECT string_agg(name || ':' || cyclomatic || ':' || loc, ',' ORDER BY ordinal) FROM function_metrics`, "Start:1:3,main:1:3"},
+		{`SELECT string_agg(import_path || ':' || coalesce(alias, '') || ':' || is_stdlib, ',' ORDER BY line) FROM import_paths`, "fmt::true,strings:str:true,example.com/dep::false"},
+		{`SELECT string_agg(a.kind, ',' ORDER BY a.depth) FROM call_sites c, ancestors(c.file_id, c.ordinal) a WHERE c.callee = 'panic'`, "*ast.ExprStmt,*ast.BlockStmt,*ast.FuncDecl,*ast.File"},
+		{`SELECT string_agg(k.kind, ',' ORDER BY k.ordinal) FROM call_sites c, children(c.file_id, c.ordinal) k WHERE c.callee = 'panic'`, "*ast.Ident,*ast.BasicLit"},
+		{`SELECT COUNT(*) FROM call_sites c, descendants(c.file_id, c.ordinal) d WHERE c.callee = 'panic'`, "2"},
+		{`SELECT string_agg(k.field || coalesce(':' || k.field_index, ''), ',' ORDER BY k.ordinal) FROM call_sites c, children(c.file_id, c.ordinal) k WHERE c.callee = 'fmt.Println'`, "Fun,Args:0,Args:1"},
+		{`SELECT string_agg(DISTINCT node_text, ',' ORDER BY node_text) FROM nodes WHERE kind IN ('*ast.GenDecl', '*ast.StarExpr')`, ",import,type"},
+	}
+	for _, tc := range cases {
+		var got string
+		if err := db.QueryRow(tc.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestRun_FailedRebuildKeepsLiveDatabase(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeGoFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	first, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	// A non-empty directory where the shadow database goes makes the rebuild fail.
+	writeGoFile(t, filepath.Join(dbPath+".rebuild", "blocker"), "x")
+	writeGoFile(t, filepath.Join(root, "extra.go"), "package main\n\nfunc extra() {}\n")
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("rolled back run: %v", err)
+	}
+	if res.Sync.Action != "rollback" || !res.Sync.RolledBack || res.Sync.RollbackError == "" {
+		t.Fatalf("expected rollback, got %+v", res.Sync)
+	}
+	if res.Sync.FilesCount != first.Sync.FilesCount {
+		t.Fatalf("expected previous index to be served, got %d files", res.Sync.FilesCount)
+	}
+
+	if err := os.RemoveAll(dbPath + ".rebuild"); err != nil {
+		t.Fatalf("remove blocker: %v", err)
+	}
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if res.Sync.Action != "rebuild" || res.Sync.FilesCount != 2 {
+		t.Fatalf("expected rebuild with 2 files, got %+v", res.Sync)
+	}
+	if _, err := os.Stat(dbPath + ".rebuild"); !os.IsNotExist(err) {
+		t.Fatalf("expected shadow database to be gone, got %v", err)
+	}
+}
+
+func TestWalkNodes_SubtreeHash(t *testing.T) {
+	t.Parallel()
+
+	src := `package p
+
+// Sum adds xs.
+func Sum(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+func Add(values []int) int {
+	acc := 10
+	for _, v := range values { acc += v }
+	return acc
+}
+
+func Sub(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		total -= x
+	}
+	return total
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	rows := walkNodes(fset, 1, file)
+	if rows[0].SubtreeSize != len(rows) {
+		t.Fatalf("root subtree_size = %d, want %d", rows[0].SubtreeSize, len(rows))
+	}
+	var funcs []nodeRow
+	for _, r := range rows {
+		if r.HasParent {
+			p := rows[r.ParentOrdinal-1]
+			if r.Ordinal <= p.Ordinal || r.Ordinal >= p.Ordinal+p.SubtreeSize {
+				t.Fatalf("node %d lies outside its parent's subtree %+v", r.Ordinal, p)
+			}
+		}
+		switch r.Kind {
+		case "*ast.FuncDecl":
+			funcs = append(funcs, r)
+		case "*ast.Ident", "*ast.BasicLit", "*ast.CommentGroup":
+			if r.HasHash {
+				t.Fatalf("%s should have no subtree_hash", r.Kind)
+			}
+		}
+	}
+	if len(funcs) != 3 || !funcs[0].HasHash {
+		t.Fatalf("unexpected functions: %+v", funcs)
+	}
+	if funcs[0].SubtreeHash != funcs[1].SubtreeHash {
+		t.Fatal("renamed copy with a different literal and layout should hash alike")
+	}
+	if funcs[0].SubtreeHash == funcs[2].SubtreeHash {
+		t.Fatal("a changed operator should change the hash")
+	}
+}
+
+func TestCollectGoFiles_SkipsTestdata(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeGoFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	writeGoFile(t, filepath.Join(root, "pkg", "testdata", "fixture", "fixture.go"), "package fixture\n\nfunc Load() {}\n")
+
+	files, err := collectGoFiles(root, "", 0)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(files) != 1 || files[0].RelPath != "main.go" {
+		t.Fatalf("expected testdata to be skipped, got %+v", files)
+	}
+
+	// A fixture directory under testdata is still indexed when it is the root.
+	db, err := BuildMemoryIndex(context.Background(), filepath.Join(root, "pkg", "testdata"))
+	if err != nil {
+		t.Fatalf("build fixture index: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	var path string
+	if err := db.QueryRow(`SELECT string_agg(path, ',') FROM files`).Scan(&path); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if path != "fixture/fixture.go" {
+		t.Fatalf("fixture index files = %q", path)
+	}
+}
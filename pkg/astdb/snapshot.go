@@ -0,0 +1,200 @@
+package astdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const snapshotExt = ".parquet"
+
+// Export writes every table of the database at dbPath as one Parquet file per
+// table into dir and returns the exported table names.
+func Export(ctx context.Context, dbPath, dir string) ([]string, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("stat db: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create export dir: %w", err)
+	}
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tables, err := listTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		target := filepath.Join(dir, table+snapshotExt)
+		stmt := fmt.Sprintf(`COPY %s TO %s (FORMAT PARQUET)`, quoteIdent(table), quoteLiteral(target))
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("export table %s: %w", table, err)
+		}
+	}
+	return tables, nil
+}
+
+// Import recreates a database at dbPath from a directory written by Export.
+// The snapshot must carry the schema version of this build.
+func Import(ctx context.Context, dir, dbPath string) error {
+	tables, err := snapshotTables(dir)
+	if err != nil {
+		return err
+	}
+	if err := checkSnapshotVersion(ctx, dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return fmt.Errorf("create db dir: %w", err)
+	}
+
+	cleanupDuckDB(dbPath)
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		return fmt.Errorf("open duckdb: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("open conn: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := createSchema(ctx, conn); err != nil {
+		return err
+	}
+	existing, err := listTables(ctx, db)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]struct{}, len(existing))
+	for _, t := range existing {
+		known[t] = struct{}{}
+	}
+
+	for _, table := range tables {
+		src := fmt.Sprintf(`read_parquet(%s)`, quoteLiteral(filepath.Join(dir, table+snapshotExt)))
+		var stmt string
+		if _, ok := known[table]; ok {
+			// Insert into the schema-defined table to keep keys and constraints.
+			stmt = fmt.Sprintf(`INSERT INTO %s BY NAME SELECT * FROM %s`, quoteIdent(table), src)
+		} else {
+			stmt = fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM %s`, quoteIdent(table), src)
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			cleanupDuckDB(dbPath)
+			return fmt.Errorf("import table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// OpenSnapshot opens an in-memory database whose tables are read-only views
+// over the Parquet files in dir.
+func OpenSnapshot(ctx context.Context, dir string) (*sql.DB, error) {
+	tables, err := snapshotTables(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSnapshotVersion(ctx, dir); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("open duckdb: %w", err)
+	}
+	for _, table := range tables {
+		stmt := fmt.Sprintf(`CREATE VIEW %s AS SELECT * FROM read_parquet(%s)`, quoteIdent(table), quoteLiteral(filepath.Join(dir, table+snapshotExt)))
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("open snapshot table %s: %w", table, err)
+		}
+	}
+	if err := createViews(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func snapshotTables(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	tables := make([]string, 0, len(entries))
+	present := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), snapshotExt) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), snapshotExt)
+		tables = append(tables, name)
+		present[name] = struct{}{}
+	}
+	sort.Strings(tables)
+	for _, required := range ownedTables {
+		if _, ok := present[required]; !ok {
+			return nil, fmt.Errorf("snapshot %s is missing table %s", dir, required)
+		}
+	}
+	return tables, nil
+}
+
+func checkSnapshotVersion(ctx context.Context, dir string) error {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return fmt.Errorf("open duckdb: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var version string
+	q := fmt.Sprintf(`SELECT value FROM read_parquet(%s) WHERE key = 'schema_version'`, quoteLiteral(filepath.Join(dir, "run_meta"+snapshotExt)))
+	if err := db.QueryRowContext(ctx, q).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("snapshot %s has no schema_version", dir)
+		}
+		return fmt.Errorf("read snapshot schema_version: %w", err)
+	}
+	if version != schemaVersion {
+		return fmt.Errorf("snapshot schema_version %s does not match %s; re-export it with this goastdb version", version, schemaVersion)
+	}
+	return nil
+}
+
+func listTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT table_name
+FROM duckdb_tables()
+WHERE database_name = current_database() AND schema_name = 'main' AND NOT temporary
+ORDER BY table_name`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
@@ -0,0 +1,124 @@
+package astdb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportImportSnapshot(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeGoFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+
+	opts := DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.Mode = "build"
+	opts.QueryBench = false
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	dir := filepath.Join(root, "snapshot")
+	tables, err := Export(context.Background(), dbPath, dir)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(tables) < 3 {
+		t.Fatalf("expected at least files, nodes and run_meta, got %v", tables)
+	}
+
+	imported := filepath.Join(root, "imported.db")
+	if err := Import(context.Background(), dir, imported); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	state, err := inspectDuckDB(imported)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if state.NodesCount != res.Sync.NodesCount || state.SchemaVersion != schemaVersion {
+		t.Fatalf("imported db mismatch: nodes=%d want %d, schema=%q", state.NodesCount, res.Sync.NodesCount, state.SchemaVersion)
+	}
+
+	db, err := OpenSnapshot(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	var n int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM nodes`).Scan(&n); err != nil {
+		t.Fatalf("query snapshot: %v", err)
+	}
+	if n != res.Sync.NodesCount {
+		t.Fatalf("snapshot nodes=%d want %d", n, res.Sync.NodesCount)
+	}
+	if _, err := db.Exec(`INSERT INTO files (file_id, path) VALUES (1, 'x.go')`); err == nil {
+		t.Fatal("expected snapshot to be read-only")
+	}
+}
+
+func TestExportImportSnapshot_GovernanceTables(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dbPath := filepath.Join(root, ".goast", "ast.db")
+	writeGoFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	opts := DefaultOptions()
+	opts.RepoRoot = root
+	opts.DuckDBPath = dbPath
+	opts.QueryBench = false
+	if _, err := Run(context.Background(), opts); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO governance_rules (rule_id, category, severity, description, query_sql, enabled, updated_unix, source, pattern)
+		 VALUES ('no-println', 'style', 'warning', 'no fmt.Println', '', true, 1, 'file:x.yaml', 'fmt.Println($*args)')`,
+		`CREATE TABLE violation_runs (run_id BIGINT PRIMARY KEY, run_unix BIGINT NOT NULL)`,
+		`INSERT INTO violation_runs VALUES (1, 1)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = db.Close()
+
+	dir := filepath.Join(root, "snapshot")
+	if _, err := Export(context.Background(), dbPath, dir); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	imported := filepath.Join(root, "imported.db")
+	if err := Import(context.Background(), dir, imported); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	db, err = sql.Open("duckdb", imported)
+	if err != nil {
+		t.Fatalf("open imported: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	var pattern string
+	var runs int
+	if err := db.QueryRow(`SELECT pattern, (SELECT COUNT(*) FROM violation_runs) FROM governance_rules WHERE rule_id = 'no-println'`).Scan(&pattern, &runs); err != nil {
+		t.Fatalf("query imported: %v", err)
+	}
+	if pattern != "fmt.Println($*args)" || runs != 1 {
+		t.Fatalf("imported governance tables mismatch: pattern=%q runs=%d", pattern, runs)
+	}
+}
+
+func TestOpenSnapshot_MissingTables(t *testing.T) {
+	t.Parallel()
+
+	if _, err := OpenSnapshot(context.Background(), t.TempDir()); err == nil {
+		t.Fatal("expected error for empty snapshot dir")
+	}
+}
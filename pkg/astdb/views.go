@@ -0,0 +1,276 @@
+package astdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Macro arguments are substituted textually, so an unqualified column passed
+// by the caller (func_name(file_id, ordinal)) would bind to the macro's own
+// tables. The macros therefore filter over projections whose columns are
+// renamed (_f, _o, _p) before comparing against their arguments.
+var viewStatements = []string{
+	`CREATE OR REPLACE VIEW func_decls AS
+WITH fd AS (
+  SELECT file_id, ordinal, start_line, end_line, start_offset, end_offset
+  FROM nodes
+  WHERE kind = '*ast.FuncDecl'
+),
+kids AS (
+  SELECT c.file_id, c.parent_ordinal AS func_ordinal, c.ordinal, c.kind, c.node_text
+  FROM nodes c
+  JOIN fd ON c.file_id = fd.file_id AND c.parent_ordinal = fd.ordinal
+),
+parts AS (
+  SELECT
+    file_id,
+    func_ordinal,
+    arg_min(node_text, ordinal) FILTER (WHERE kind = '*ast.Ident') AS name,
+    min(ordinal) FILTER (WHERE kind = '*ast.FieldList') AS recv_ordinal,
+    min(ordinal) FILTER (WHERE kind = '*ast.FuncType') AS type_ordinal,
+    min(ordinal) FILTER (WHERE kind = '*ast.BlockStmt') AS body_ordinal
+  FROM kids
+  GROUP BY file_id, func_ordinal
+),
+recv_type_nodes AS (
+  SELECT p.file_id, p.func_ordinal, max(t.ordinal) AS type_node
+  FROM parts p
+  JOIN nodes fld ON fld.file_id = p.file_id AND fld.parent_ordinal = p.recv_ordinal AND fld.kind = '*ast.Field'
+  JOIN nodes t ON t.file_id = fld.file_id AND t.parent_ordinal = fld.ordinal
+  GROUP BY p.file_id, p.func_ordinal
+),
+recv_types AS (
+  SELECT r.file_id, r.func_ordinal, arg_min(i.node_text, i.ordinal) AS receiver
+  FROM recv_type_nodes r
+  JOIN nodes t ON t.file_id = r.file_id AND t.ordinal = r.type_node
+  JOIN nodes i
+    ON i.file_id = r.file_id
+   AND i.kind = '*ast.Ident'
+   AND i.ordinal >= r.type_node
+   AND i.start_offset >= t.start_offset
+   AND i.end_offset <= t.end_offset
+  GROUP BY r.file_id, r.func_ordinal
+)
+SELECT
+  fd.file_id,
+  fd.ordinal,
+  f.path,
+  f.pkg_name,
+  coalesce(p.name, '<anonymous>') AS name,
+  rt.receiver,
+  p.recv_ordinal IS NOT NULL AS is_method,
+  regexp_matches(coalesce(p.name, ''), '^[A-Z]') AS is_exported,
+  fd.start_line,
+  fd.end_line,
+  fd.end_line - fd.start_line + 1 AS line_span,
+  fd.start_offset,
+  fd.end_offset,
+  p.type_ordinal,
+  p.body_ordinal
+FROM fd
+JOIN files f ON f.file_id = fd.file_id
+LEFT JOIN parts p ON p.file_id = fd.file_id AND p.func_ordinal = fd.ordinal
+LEFT JOIN recv_types rt ON rt.file_id = fd.file_id AND rt.func_ordinal = fd.ordinal`,
+
+	`CREATE OR REPLACE VIEW functions AS SELECT * EXCLUDE (receiver, is_method) FROM func_decls WHERE NOT is_method`,
+
+	`CREATE OR REPLACE VIEW methods AS SELECT * EXCLUDE (is_method) FROM func_decls WHERE is_method`,
+
+	`CREATE OR REPLACE VIEW type_decls AS
+WITH ts AS (
+  SELECT file_id, ordinal, start_line, end_line, start_offset, end_offset
+  FROM nodes
+  WHERE kind = '*ast.TypeSpec'
+),
+parts AS (
+  SELECT
+    c.file_id,
+    c.parent_ordinal AS spec_ordinal,
+    arg_min(c.node_text, c.ordinal) FILTER (WHERE c.kind = '*ast.Ident') AS name,
+    max(c.ordinal) FILTER (WHERE c.kind <> '*ast.CommentGroup') AS type_ordinal
+  FROM nodes c
+  JOIN ts ON c.file_id = ts.file_id AND c.parent_ordinal = ts.ordinal
+  GROUP BY c.file_id, c.parent_ordinal
+)
+SELECT
+  ts.file_id,
+  ts.ordinal,
+  f.path,
+  f.pkg_name,
+  p.name,
+  t.kind AS type_kind,
+  p.type_ordinal,
+  regexp_matches(coalesce(p.name, ''), '^[A-Z]') AS is_exported,
+  ts.start_line,
+  ts.end_line,
+  ts.start_offset,
+  ts.end_offset
+FROM ts
+JOIN files f ON f.file_id = ts.file_id
+JOIN parts p ON p.file_id = ts.file_id AND p.spec_ordinal = ts.ordinal
+JOIN nodes t ON t.file_id = ts.file_id AND t.ordinal = p.type_ordinal`,
+
+	`CREATE OR REPLACE VIEW struct_types AS
+SELECT td.* EXCLUDE (type_kind), COUNT(fd.ordinal) AS field_count
+FROM type_decls td
+LEFT JOIN nodes fl ON fl.file_id = td.file_id AND fl.parent_ordinal = td.type_ordinal AND fl.kind = '*ast.FieldList'
+LEFT JOIN nodes fd ON fd.file_id = fl.file_id AND fd.parent_ordinal = fl.ordinal AND fd.kind = '*ast.Field'
+WHERE td.type_kind = '*ast.StructType'
+GROUP BY ALL`,
+
+	`CREATE OR REPLACE VIEW interfaces AS
+SELECT td.* EXCLUDE (type_kind), COUNT(fd.ordinal) AS method_count
+FROM type_decls td
+LEFT JOIN nodes fl ON fl.file_id = td.file_id AND fl.parent_ordinal = td.type_ordinal AND fl.kind = '*ast.FieldList'
+LEFT JOIN nodes fd ON fd.file_id = fl.file_id AND fd.parent_ordinal = fl.ordinal AND fd.kind = '*ast.Field'
+WHERE td.type_kind = '*ast.InterfaceType'
+GROUP BY ALL`,
+
+	`CREATE OR REPLACE VIEW package_symbols AS
+WITH decls AS (
+  SELECT file_id, ordinal, 'func' AS tok, start_line, end_line, subtree_size
+  FROM nodes
+  WHERE field = 'Decls' AND kind = '*ast.FuncDecl'
+  UNION ALL
+  SELECT s.file_id, s.ordinal, g.node_text, s.start_line, s.end_line, s.subtree_size
+  FROM nodes g
+  JOIN nodes s ON s.file_id = g.file_id AND s.parent_ordinal = g.ordinal AND s.field = 'Specs'
+  WHERE g.field = 'Decls' AND g.kind = '*ast.GenDecl' AND g.node_text IN ('type', 'var', 'const')
+)
+SELECT
+  d.file_id,
+  d.ordinal,
+  n.ordinal AS name_ordinal,
+  f.path,
+  f.pkg_name,
+  CASE WHEN contains(f.path, '/') THEN regexp_replace(f.path, '/[^/]*$', '') ELSE '.' END AS pkg_dir,
+  CASE WHEN fd.is_method THEN 'method' ELSE d.tok END AS symbol_kind,
+  n.node_text AS name,
+  fd.receiver,
+  regexp_matches(n.node_text, '^[A-Z]') AS is_exported,
+  ends_with(f.path, '_test.go') AS is_test_file,
+  d.start_line,
+  d.end_line,
+  d.subtree_size
+FROM decls d
+JOIN files f ON f.file_id = d.file_id
+JOIN nodes n ON n.file_id = d.file_id AND n.parent_ordinal = d.ordinal AND n.field IN ('Name', 'Names')
+LEFT JOIN func_decls fd ON fd.file_id = d.file_id AND fd.ordinal = d.ordinal
+WHERE n.node_text <> '_'`,
+
+	`CREATE OR REPLACE VIEW call_sites AS
+WITH calls AS (
+  SELECT file_id, ordinal, start_line, start_col, end_line, end_col, start_offset, end_offset
+  FROM nodes
+  WHERE kind = '*ast.CallExpr'
+),
+call_parts AS (
+  SELECT c.file_id, c.ordinal AS call_ordinal, min(k.ordinal) AS fun_ordinal, COUNT(*) - 1 AS arg_count
+  FROM calls c
+  JOIN nodes k ON k.file_id = c.file_id AND k.parent_ordinal = c.ordinal
+  GROUP BY c.file_id, c.ordinal
+),
+selector_parts AS (
+  SELECT s.file_id, s.parent_ordinal AS sel_ordinal, min(s.ordinal) AS x_ordinal, max(s.ordinal) AS name_ordinal
+  FROM nodes s
+  JOIN call_parts cp ON cp.file_id = s.file_id AND cp.fun_ordinal = s.parent_ordinal
+  GROUP BY s.file_id, s.parent_ordinal
+)
+SELECT
+  c.file_id,
+  c.ordinal,
+  f.path,
+  f.pkg_name,
+  CASE WHEN x.kind = '*ast.Ident' THEN x.node_text END AS qualifier,
+  CASE fun.kind
+    WHEN '*ast.Ident' THEN fun.node_text
+    WHEN '*ast.SelectorExpr' THEN sel.node_text
+  END AS callee_name,
+  CASE fun.kind
+    WHEN '*ast.Ident' THEN fun.node_text
+    WHEN '*ast.SelectorExpr' THEN coalesce(CASE WHEN x.kind = '*ast.Ident' THEN x.node_text || '.' END, '') || sel.node_text
+  END AS callee,
+  fun.kind AS fun_kind,
+  cp.arg_count,
+  c.start_line,
+  c.start_col,
+  c.end_line,
+  c.end_col,
+  c.start_offset,
+  c.end_offset
+FROM calls c
+JOIN files f ON f.file_id = c.file_id
+JOIN call_parts cp ON cp.file_id = c.file_id AND cp.call_ordinal = c.ordinal
+JOIN nodes fun ON fun.file_id = c.file_id AND fun.ordinal = cp.fun_ordinal
+LEFT JOIN selector_parts sp ON fun.kind = '*ast.SelectorExpr' AND sp.file_id = c.file_id AND sp.sel_ordinal = fun.ordinal
+LEFT JOIN nodes x ON x.file_id = sp.file_id AND x.ordinal = sp.x_ordinal
+LEFT JOIN nodes sel ON sel.file_id = sp.file_id AND sel.ordinal = sp.name_ordinal`,
+
+	`CREATE OR REPLACE VIEW import_paths AS
+SELECT
+  n.file_id,
+  n.ordinal,
+  f.path,
+  f.pkg_name,
+  trim(coalesce(n.node_text, ''), '"` + "`" + `') AS import_path,
+  (SELECT a.node_text FROM nodes a WHERE a.file_id = n.file_id AND a.parent_ordinal = n.ordinal AND a.kind = '*ast.Ident') AS alias,
+  NOT contains(split_part(trim(coalesce(n.node_text, ''), '"` + "`" + `'), '/', 1), '.') AS is_stdlib,
+  n.start_line AS line
+FROM nodes n
+JOIN files f ON f.file_id = n.file_id
+WHERE n.kind = '*ast.ImportSpec'`,
+
+	`CREATE OR REPLACE MACRO children(fid, ord) AS TABLE
+SELECT n.*
+FROM nodes n
+JOIN (
+  SELECT _f, _o FROM (SELECT file_id AS _f, ordinal AS _o, parent_ordinal AS _p FROM nodes) WHERE _f = fid AND _p = ord
+) k ON n.file_id = k._f AND n.ordinal = k._o
+ORDER BY n.ordinal`,
+
+	`CREATE OR REPLACE MACRO descendants(fid, ord) AS TABLE
+SELECT n.*
+FROM nodes n
+JOIN (
+  SELECT _f, _o, _s, _e FROM (SELECT file_id AS _f, ordinal AS _o, start_offset AS _s, end_offset AS _e FROM nodes) WHERE _f = fid AND _o = ord
+) k ON n.file_id = k._f AND n.ordinal > k._o AND n.start_offset >= k._s AND n.end_offset <= k._e
+ORDER BY n.ordinal`,
+
+	`CREATE OR REPLACE MACRO ancestors(fid, ord) AS TABLE
+WITH RECURSIVE chain(file_id, ordinal, depth) AS (
+  SELECT _f, _p, 1 FROM (SELECT file_id AS _f, ordinal AS _o, parent_ordinal AS _p FROM nodes) WHERE _f = fid AND _o = ord AND _p IS NOT NULL
+  UNION ALL
+  SELECT n.file_id, n.parent_ordinal, c.depth + 1
+  FROM chain c
+  JOIN nodes n ON n.file_id = c.file_id AND n.ordinal = c.ordinal
+  WHERE n.parent_ordinal IS NOT NULL
+)
+SELECT n.*, c.depth
+FROM chain c
+JOIN nodes n ON n.file_id = c.file_id AND n.ordinal = c.ordinal
+ORDER BY c.depth`,
+
+	`CREATE OR REPLACE MACRO func_name(fid, ord) AS (
+  SELECT arg_max(fd._name, fd._s)
+  FROM (SELECT file_id AS _f, name AS _name, start_offset AS _s, end_offset AS _e FROM func_decls) fd
+  JOIN (SELECT file_id AS _nf, ordinal AS _no, start_offset AS _ns, end_offset AS _ne FROM nodes) nd
+    ON fd._f = nd._nf AND fd._s <= nd._ns AND fd._e >= nd._ne
+  WHERE nd._nf = fid AND nd._no = ord
+)`,
+}
+
+// createViews defines the documented query API over the index tables. It is
+// also run against Parquet snapshots, whose tables are views themselves.
+func createViews(ctx context.Context, db execer) error {
+	for _, stmt := range viewStatements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create views: %w", err)
+		}
+	}
+	return nil
+}